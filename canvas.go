@@ -0,0 +1,62 @@
+package eicplot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// LengthFlag accepts lengths in the units understood by vg.ParseLength, such
+// as "6in" or "15cm".  Plain numbers are in points.
+type LengthFlag struct {
+	Length vg.Length
+}
+
+func (f *LengthFlag) Set(valueStr string) error {
+	length, err := vg.ParseLength(valueStr)
+	if err != nil {
+		return err
+	}
+	if length <= 0 {
+		return fmt.Errorf("length must be positive: %q", valueStr)
+	}
+	f.Length = length
+	return nil
+}
+
+func (f *LengthFlag) String() string {
+	return fmt.Sprintf("%vpt", float64(f.Length))
+}
+
+// NewFlagCanvasSize registers the -width and -height command-line flags.
+func NewFlagCanvasSize(width, height vg.Length) (*LengthFlag, *LengthFlag) {
+	w := &LengthFlag{Length: width}
+	h := &LengthFlag{Length: height}
+	flag.Var(w, "width", "width of the output canvas (e.g. 670, 6in or 15cm)")
+	flag.Var(h, "height", "height of the output canvas (e.g. 400, 4in or 10cm)")
+	return w, h
+}
+
+// NewCanvas returns a canvas of the given size for the format implied by the
+// extension of filename: eps, jpg, pdf, png, svg or tif.
+func NewCanvas(filename string, w, h vg.Length) (vg.CanvasWriterTo, error) {
+	format := strings.ToLower(strings.TrimPrefix(filepath.Ext(filename), "."))
+	return draw.NewFormattedCanvas(w, h, format)
+}
+
+func SaveCanvas(c vg.CanvasWriterTo, filename string) error {
+	w, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if _, err := c.WriteTo(w); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
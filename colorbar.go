@@ -0,0 +1,53 @@
+package eicplot
+
+import (
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// ColorBar is a vertical color bar for a ColorMap.  Unlike plotter.ColorBar,
+// it is drawn from filled rectangles rather than an image, so that it
+// renders in every vector format.
+type ColorBar struct {
+	ColorMap palette.ColorMap
+
+	// Colors is the number of color steps drawn, default 256.
+	Colors int
+}
+
+func (b *ColorBar) Plot(c draw.Canvas, p *plot.Plot) {
+	nColors := b.Colors
+	if nColors <= 0 {
+		nColors = 256
+	}
+
+	trX, trY := p.Transforms(&c)
+	xMin, xMax := trX(0), trX(1)
+	min, max := b.ColorMap.Min(), b.ColorMap.Max()
+	delta := (max - min) / float64(nColors)
+	for i := 0; i < nColors; i++ {
+		color, err := b.ColorMap.At(min + delta*(float64(i)+0.5))
+		if err != nil {
+			continue
+		}
+
+		yMin := trY(min + delta*float64(i))
+		yMax := trY(min + delta*float64(i+1))
+		// overlap neighbouring steps slightly to hide antialiasing seams
+		if i < nColors-1 {
+			yMax += 0.5
+		}
+		c.FillPolygon(color, []vg.Point{
+			{X: xMin, Y: yMin},
+			{X: xMax, Y: yMin},
+			{X: xMax, Y: yMax},
+			{X: xMin, Y: yMax},
+		})
+	}
+}
+
+func (b *ColorBar) DataRange() (xmin, xmax, ymin, ymax float64) {
+	return 0, 1, b.ColorMap.Min(), b.ColorMap.Max()
+}
@@ -0,0 +1,125 @@
+package eicplot
+
+import (
+	"flag"
+	"fmt"
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/gonum/stat/distuv"
+)
+
+// Compatibility holds the results of testing whether two histograms have
+// the same shape.
+type Compatibility struct {
+	Chi2     float64
+	NDF      int
+	Chi2Prob float64
+
+	KSDist float64
+	KSProb float64
+}
+
+// Compare tests the shape of h against that of ref, which must have identical
+// binning.  Only the in-range bins are used.
+func Compare(h, ref *hbook.H1D) Compatibility {
+	var c Compatibility
+	c.Chi2, c.NDF, c.Chi2Prob = Chi2Test(h, ref)
+	c.KSDist, c.KSProb = KSTest(h, ref)
+	return c
+}
+
+func (c Compatibility) String() string {
+	return fmt.Sprintf("chi2/ndf = %.4g/%d, p(chi2) = %.3g, KS D = %.3g, p(KS) = %.3g", c.Chi2, c.NDF, c.Chi2Prob, c.KSDist, c.KSProb)
+}
+
+// Summary gives just the p-values, for legends.
+func (c Compatibility) Summary() string {
+	return fmt.Sprintf("chi2 p = %.2g, KS p = %.2g", c.Chi2Prob, c.KSProb)
+}
+
+// Chi2Test compares the bin contents of h and ref as counts of independent
+// entries with different normalizations, using the pooled estimate of each
+// bin's probability for the variance.  Bins empty in both are skipped, and one
+// degree of freedom is taken by the normalization.
+func Chi2Test(h, ref *hbook.H1D) (chi2 float64, ndf int, p float64) {
+	sum1, sum2 := inRangeSumW(h), inRangeSumW(ref)
+	if sum1 <= 0 || sum2 <= 0 {
+		return 0, 0, 1
+	}
+
+	for i := range h.Binning.Bins {
+		n1, n2 := h.Binning.Bins[i].SumW(), ref.Binning.Bins[i].SumW()
+		if n1+n2 <= 0 {
+			continue
+		}
+		diff := sum2*n1 - sum1*n2
+		chi2 += diff * diff / (n1 + n2)
+		ndf++
+	}
+	chi2 /= sum1 * sum2
+	ndf--
+
+	if ndf < 1 {
+		return chi2, 0, 1
+	}
+	return chi2, ndf, distuv.ChiSquared{K: float64(ndf)}.Survival(chi2)
+}
+
+// KSTest returns the Kolmogorov-Smirnov distance between the cumulative
+// distributions of h and ref, and its asymptotic p-value using the effective
+// numbers of entries.  Binning makes the p-value conservative.
+func KSTest(h, ref *hbook.H1D) (dist, p float64) {
+	sum1, sum2 := inRangeSumW(h), inRangeSumW(ref)
+	if sum1 <= 0 || sum2 <= 0 {
+		return 0, 1
+	}
+
+	var cum1, cum2, sumW21, sumW22 float64
+	for i := range h.Binning.Bins {
+		bin1, bin2 := h.Binning.Bins[i], ref.Binning.Bins[i]
+		cum1 += bin1.SumW() / sum1
+		cum2 += bin2.SumW() / sum2
+		dist = math.Max(dist, math.Abs(cum1-cum2))
+		sumW21 += bin1.SumW2()
+		sumW22 += bin2.SumW2()
+	}
+
+	n1 := sum1 * sum1 / sumW21
+	n2 := sum2 * sum2 / sumW22
+	sqrtN := math.Sqrt(n1 * n2 / (n1 + n2))
+	return dist, kolmogorovProb((sqrtN + 0.12 + 0.11/sqrtN) * dist)
+}
+
+func inRangeSumW(h *hbook.H1D) float64 {
+	var sum float64
+	for _, bin := range h.Binning.Bins {
+		sum += bin.SumW()
+	}
+	return sum
+}
+
+// kolmogorovProb is the survival function of the Kolmogorov distribution.
+func kolmogorovProb(lambda float64) float64 {
+	if lambda < 0.2 {
+		return 1
+	}
+
+	var sum float64
+	sign := 1.
+	for j := 1; j <= 100; j++ {
+		term := sign * math.Exp(-2*float64(j*j)*lambda*lambda)
+		sum += term
+		if math.Abs(term) < 1e-10*math.Abs(sum) {
+			break
+		}
+		sign = -sign
+	}
+	return math.Max(math.Min(2*sum, 1), 0)
+}
+
+// NewFlagCompare registers the -compare and -drawcompare command-line flags.
+func NewFlagCompare() (*bool, *bool) {
+	return flag.Bool("compare", false, "print chi-square and Kolmogorov-Smirnov p-values against the reference input file"),
+		flag.Bool("drawcompare", false, "also show the p-values in the legend")
+}
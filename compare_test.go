@@ -0,0 +1,77 @@
+package eicplot
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+func TestKolmogorovProb(t *testing.T) {
+	tests := []struct {
+		lambda, p float64
+	}{
+		{0, 1},
+		{0.5, 0.9639452436648751},
+		{1, 0.26999967167735456},
+		{1.36, 0.049485876755377876},
+		{2, 0.0006709252557796953},
+	}
+
+	for _, test := range tests {
+		if p := kolmogorovProb(test.lambda); math.Abs(p-test.p) > 1e-9 {
+			t.Errorf("kolmogorovProb(%v) = %v, want %v", test.lambda, p, test.p)
+		}
+	}
+}
+
+func TestChi2TestCounts(t *testing.T) {
+	h := hbook.NewH1D(3, 0, 3)
+	ref := hbook.NewH1D(3, 0, 3)
+	h.Fill(0.5, 10)
+	h.Fill(1.5, 20)
+	ref.Fill(0.5, 20)
+	ref.Fill(1.5, 10)
+	// outflows are not compared, and the last bin, empty in both, takes no
+	// degree of freedom
+	h.Fill(-1, 5)
+
+	chi2, ndf, p := Chi2Test(h, ref)
+	if math.Abs(chi2-20./3) > 1e-9 || ndf != 1 || math.Abs(p-0.009823274507519245) > 1e-9 {
+		t.Errorf("Chi2Test = %v, %v, %v, want %v, 1, 0.00982", chi2, ndf, p, 20./3)
+	}
+}
+
+func gaussHist(rnd *rand.Rand, n int, mean float64) *hbook.H1D {
+	h := hbook.NewH1D(40, -4, 4)
+	for i := 0; i < n; i++ {
+		h.Fill(rnd.NormFloat64()+mean, 1)
+	}
+	return h
+}
+
+func TestCompare(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	ref := gaussHist(rnd, 2000, 0)
+
+	c := Compare(ref, ref)
+	if c.Chi2 != 0 || c.KSDist != 0 || c.Chi2Prob != 1 || c.KSProb != 1 {
+		t.Errorf("identical histograms: %v, want chi2 and KS D of 0 and p-values of 1", c)
+	}
+
+	// a scaled copy has the same shape
+	scaled := gaussHist(rand.New(rand.NewSource(1)), 2000, 0)
+	scaled.Scale(3)
+	if c := Compare(scaled, ref); c.Chi2Prob < 0.999 || c.KSProb < 0.999 {
+		t.Errorf("scaled histogram: %v, want p-values near 1", c)
+	}
+
+	if c := Compare(gaussHist(rnd, 2000, 0), ref); c.Chi2Prob < 0.01 || c.KSProb < 0.01 {
+		t.Errorf("same distribution: %v, want p-values above 0.01", c)
+	}
+
+	if c := Compare(gaussHist(rnd, 2000, 0.2), ref); c.Chi2Prob > 1e-3 || c.KSProb > 1e-3 {
+		t.Errorf("shifted distribution: %v, want p-values below 0.001", c)
+	}
+}
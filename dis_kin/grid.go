@@ -0,0 +1,257 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+)
+
+// Method selects the reconstruction of the DIS kinematics.
+type Method int
+
+const (
+	Electron Method = iota
+	JacquetBlondel
+	DoubleAngle
+	nMethods
+)
+
+var methodNames = map[Method]string{
+	Electron:       "electron",
+	JacquetBlondel: "jb",
+	DoubleAngle:    "da",
+}
+
+func (m *Method) Set(valueStr string) error {
+	for method, name := range methodNames {
+		if name == valueStr {
+			*m = method
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown reconstruction method %q", valueStr)
+}
+
+func (m *Method) String() string {
+	return methodNames[*m]
+}
+
+// Variable selects one of the DIS kinematic variables.
+type Variable int
+
+const (
+	X Variable = iota
+	Q2
+	Y
+	W
+)
+
+var variableNames = map[Variable]string{
+	X:  "x",
+	Q2: "q2",
+	Y:  "y",
+	W:  "w",
+}
+
+var variableLabels = map[Variable]string{
+	X:  "x",
+	Q2: "Q^2",
+	Y:  "y",
+	W:  "W",
+}
+
+func (v *Variable) Set(valueStr string) error {
+	for variable, name := range variableNames {
+		if name == valueStr {
+			*v = variable
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown kinematic variable %q", valueStr)
+}
+
+func (v *Variable) String() string {
+	return variableNames[*v]
+}
+
+func (v Variable) Value(d kin.DIS) float64 {
+	switch v {
+	case Q2:
+		return d.Q2
+	case Y:
+		return d.Y
+	case W:
+		return d.W
+	default:
+		return d.X
+	}
+}
+
+// PlotKind selects the quantity shown in each x-Q^2 bin.
+type PlotKind int
+
+const (
+	// Resolution is half the width of the central interval containing 68% of
+	// the relative differences between reconstructed and true values, in bins
+	// of the true kinematics.
+	Resolution PlotKind = iota
+	// Purity is the fraction of events reconstructed in a bin that were
+	// generated in it.
+	Purity
+	// Stability is the fraction of events generated in a bin, and
+	// reconstructed anywhere, that were reconstructed in it.
+	Stability
+)
+
+var plotKindNames = map[PlotKind]string{
+	Resolution: "resolution",
+	Purity:     "purity",
+	Stability:  "stability",
+}
+
+func (p *PlotKind) Set(valueStr string) error {
+	for kind, name := range plotKindNames {
+		if name == valueStr {
+			*p = kind
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown plot %q", valueStr)
+}
+
+func (p *PlotKind) String() string {
+	return plotKindNames[*p]
+}
+
+// eventKin holds the true kinematics of an event and those reconstructed by
+// each method.
+type eventKin struct {
+	True  kin.DIS
+	Rec   [nMethods]kin.DIS
+	Valid [nMethods]bool
+}
+
+// KinGrid holds a value for each bin in log10(x) and log10(Q^2).
+type KinGrid struct {
+	NBinsX, NBinsQ2    int
+	LogXMin, LogXMax   float64
+	LogQ2Min, LogQ2Max float64
+	values             []float64
+}
+
+func NewKinGrid(nBinsX int, xMin, xMax float64, nBinsQ2 int, q2Min, q2Max float64) *KinGrid {
+	g := &KinGrid{
+		NBinsX:   nBinsX,
+		NBinsQ2:  nBinsQ2,
+		LogXMin:  math.Log10(xMin),
+		LogXMax:  math.Log10(xMax),
+		LogQ2Min: math.Log10(q2Min),
+		LogQ2Max: math.Log10(q2Max),
+		values:   make([]float64, nBinsX*nBinsQ2),
+	}
+	for i := range g.values {
+		g.values[i] = math.NaN()
+	}
+	return g
+}
+
+// Bin returns the index of the bin containing d.
+func (g *KinGrid) Bin(d kin.DIS) (int, bool) {
+	logX, logQ2 := math.Log10(d.X), math.Log10(d.Q2)
+	if !(logX >= g.LogXMin && logX < g.LogXMax && logQ2 >= g.LogQ2Min && logQ2 < g.LogQ2Max) {
+		return 0, false
+	}
+	i := int(float64(g.NBinsX) * (logX - g.LogXMin) / (g.LogXMax - g.LogXMin))
+	j := int(float64(g.NBinsQ2) * (logQ2 - g.LogQ2Min) / (g.LogQ2Max - g.LogQ2Min))
+	return j*g.NBinsX + i, true
+}
+
+// FillResolution sets each bin to the resolution of variable reconstructed
+// by method, and returns the number of events used in each bin.
+func (g *KinGrid) FillResolution(events []eventKin, method Method, variable Variable) []int {
+	binValues := make([][]float64, len(g.values))
+	for _, event := range events {
+		bin, ok := g.Bin(event.True)
+		if !ok || !event.Valid[method] {
+			continue
+		}
+		trueValue := variable.Value(event.True)
+		binValues[bin] = append(binValues[bin], variable.Value(event.Rec[method])/trueValue-1)
+	}
+
+	counts := make([]int, len(g.values))
+	for i, values := range binValues {
+		counts[i] = len(values)
+		g.values[i], _ = eicplot.Central68Width(values)
+	}
+	return counts
+}
+
+// FillPurity sets each bin to the purity or stability of method, and returns
+// the number of events reconstructed or generated in each bin.
+func (g *KinGrid) FillPurity(events []eventKin, method Method, kind PlotKind) []int {
+	nGen := make([]int, len(g.values))
+	nRec := make([]int, len(g.values))
+	nBoth := make([]int, len(g.values))
+	for _, event := range events {
+		if !event.Valid[method] {
+			continue
+		}
+		genBin, genOK := g.Bin(event.True)
+		recBin, recOK := g.Bin(event.Rec[method])
+		if genOK {
+			nGen[genBin]++
+		}
+		if recOK {
+			nRec[recBin]++
+		}
+		if genOK && recOK && genBin == recBin {
+			nBoth[genBin]++
+		}
+	}
+
+	counts := nRec
+	if kind == Stability {
+		counts = nGen
+	}
+	for i := range g.values {
+		if counts[i] > 0 {
+			g.values[i] = float64(nBoth[i]) / float64(counts[i])
+		}
+	}
+	return counts
+}
+
+func (g *KinGrid) Dims() (int, int) {
+	return g.NBinsX, g.NBinsQ2
+}
+
+func (g *KinGrid) Z(i, j int) float64 {
+	return g.values[j*g.NBinsX+i]
+}
+
+func (g *KinGrid) X(i int) float64 {
+	return g.LogXMin + (float64(i)+0.5)*(g.LogXMax-g.LogXMin)/float64(g.NBinsX)
+}
+
+func (g *KinGrid) Y(j int) float64 {
+	return g.LogQ2Min + (float64(j)+0.5)*(g.LogQ2Max-g.LogQ2Min)/float64(g.NBinsQ2)
+}
+
+// H2D returns a histogram in log10(x) and log10(Q^2) with the content of each
+// bin set to values, leaving out bins without a value.
+func (g *KinGrid) H2D(values func(i, j int) float64) *hbook.H2D {
+	h := hbook.NewH2D(g.NBinsX, g.LogXMin, g.LogXMax, g.NBinsQ2, g.LogQ2Min, g.LogQ2Max)
+	for i := 0; i < g.NBinsX; i++ {
+		for j := 0; j < g.NBinsQ2; j++ {
+			if v := values(i, j); !math.IsNaN(v) {
+				h.Fill(g.X(i), g.Y(j), v)
+			}
+		}
+	}
+	return h
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"gonum.org/v1/plot/palette/moreland"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
+)
+
+var (
+	nBinsX   = flag.Int("nbinsx", 8, "number of bins in log10(x)")
+	xMin     = flag.Float64("xmin", 1e-4, "minimum x")
+	xMax     = flag.Float64("xmax", 1, "maximum x")
+	nBinsQ2  = flag.Int("nbinsq2", 6, "number of bins in log10(Q^2)")
+	q2Min    = flag.Float64("q2min", 1, "minimum Q^2 (GeV^2)")
+	q2Max    = flag.Float64("q2max", 1000, "maximum Q^2 (GeV^2)")
+	resLimit = flag.Float64("reslimit", 0.2, "maximum relative resolution in the color map")
+	title    = flag.String("title", "", "plot title")
+	output   = flag.String("output", "out.png", "output file")
+	nWorkers = eicplot.NewFlagWorkers()
+	histsOut = eicplot.NewFlagHistFile()
+	matcher  = truth.NewFlagMatcher()
+
+	beams, beamMeta = kin.NewFlagBeamSetup()
+	width, height   = eicplot.NewFlagCanvasSize(670, 400)
+
+	plotKind PlotKind
+	method   Method
+	variable Variable
+)
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+// leptonPDG gives the PDG code of each lepton beam species.
+var leptonPDG = map[string]int32{
+	"e-":  11,
+	"e+":  -11,
+	"mu-": 13,
+	"mu+": -13,
+}
+
+func main() {
+	flag.Var(&plotKind, "plot", "quantity to plot in x-Q^2 bins (resolution, purity or stability)")
+	flag.Var(&method, "method", "kinematics reconstruction method (electron, jb or da)")
+	flag.Var(&variable, "var", "variable for the resolution plot (x, q2, y or w)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+	if *nBinsX < 1 || *nBinsQ2 < 1 || !(*xMin > 0 && *xMax > *xMin) || !(*q2Min > 0 && *q2Max > *q2Min) {
+		log.Fatal("Invalid x-Q^2 binning")
+	}
+
+	workerEvents := make([][]eventKin, *nWorkers)
+	nFound := make([]int, *nWorkers)
+	nCorrect := make([]int, *nWorkers)
+
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		// start from the flags for each event, as events of files with and
+		// without beam metadata are interleaved
+		beams := *beams
+		if *beamMeta {
+			if err := beams.ReadMetadata(event.Metadata); err != nil {
+				log.Fatal(err)
+			}
+		}
+		pdg, ok := leptonPDG[beams.Lepton.Name]
+		if !ok {
+			log.Fatalf("Lepton beam %v is not a lepton", beams.Lepton.Name)
+		}
+		lepton := beams.LeptonBeam()
+		hadron := beams.HadronBeam()
+		nucleons := float64(beams.Hadron.Nucleons)
+		hadron = kin.Vec4{P: hadron.P.Scale(1 / nucleons), E: hadron.E / nucleons}
+
+		// the true scattered lepton is the most energetic one of the beam
+		// species
+		var trueLepton *eic.Particle
+		var trueID uint64
+		for _, id := range event.TaggedEntries("GenStable") {
+			part, ok := event.GetEntry(id).(*eic.Particle)
+			if ok && part.GetPdg() == pdg && (trueLepton == nil || kin.FromParticle(part).E > kin.FromParticle(trueLepton).E) {
+				trueLepton, trueID = part, id
+			}
+		}
+		if trueLepton == nil {
+			return
+		}
+
+		var ev eventKin
+		ev.True, ok = kin.DISFromElectron(lepton, hadron, kin.FromParticle(trueLepton))
+		if !ok {
+			return
+		}
+
+		// the scattered lepton candidate is the highest-momentum track with
+		// the lepton's charge, and the other tracks make up the hadronic
+		// final state
+		var tracks []*eic.Track
+		var candidate *eic.Track
+		for _, id := range event.TaggedEntries("Reconstructed") {
+			track, ok := event.GetEntry(id).(*eic.Track)
+			if !ok || len(track.Segment) == 0 {
+				continue
+			}
+			tracks = append(tracks, track)
+			if (track.Segment[0].GetChargesign() < 0) != (pdg > 0) {
+				continue
+			}
+			if candidate == nil || kin.Vec3FromXYZD(track.Segment[0].Poq).Mag() > kin.Vec3FromXYZD(candidate.Segment[0].Poq).Mag() {
+				candidate = track
+			}
+		}
+
+		if candidate != nil {
+			nFound[worker]++
+			if matcher.Match(event, candidate).ParticleID == trueID {
+				nCorrect[worker]++
+			}
+
+			scattered := kin.FromTrackSegment(candidate.Segment[0], beams.Lepton.Mass)
+			var hadrons kin.Vec4
+			for _, track := range tracks {
+				if track != candidate {
+					hadrons = hadrons.Add(beams.HeadOn(kin.FromTrackSegment(track.Segment[0], kin.PionMass)))
+				}
+			}
+			headOnLepton, headOnHadron := beams.HeadOn(lepton), beams.HeadOn(hadron)
+
+			ev.Rec[Electron], ev.Valid[Electron] = kin.DISFromElectron(lepton, hadron, scattered)
+			ev.Rec[JacquetBlondel], ev.Valid[JacquetBlondel] = kin.DISJacquetBlondel(headOnLepton, headOnHadron, hadrons)
+			ev.Rec[DoubleAngle], ev.Valid[DoubleAngle] = kin.DISDoubleAngle(headOnLepton, headOnHadron, beams.HeadOn(scattered), hadrons)
+		}
+
+		workerEvents[worker] = append(workerEvents[worker], ev)
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var events []eventKin
+	var found, correct int
+	for i := range workerEvents {
+		events = append(events, workerEvents[i]...)
+		found += nFound[i]
+		correct += nCorrect[i]
+	}
+	fmt.Printf("scattered lepton candidate in %v of %v events, matched to the true one in %v\n", found, len(events), correct)
+	for m := Electron; m < nMethods; m++ {
+		var dx, dq2 []float64
+		for _, ev := range events {
+			if ev.Valid[m] {
+				dx = append(dx, ev.Rec[m].X/ev.True.X-1)
+				dq2 = append(dq2, ev.Rec[m].Q2/ev.True.Q2-1)
+			}
+		}
+		xRes, _ := eicplot.Central68Width(dx)
+		q2Res, _ := eicplot.Central68Width(dq2)
+		fmt.Printf("%v: %v events, x resolution %.3g, Q^2 resolution %.3g\n", m.String(), len(dx), xRes, q2Res)
+	}
+
+	grid := NewKinGrid(*nBinsX, *xMin, *xMax, *nBinsQ2, *q2Min, *q2Max)
+	var counts []int
+	zLabel := plotKind.String() + " (" + method.String() + ")"
+	zMax := 1.
+	if plotKind == Resolution {
+		counts = grid.FillResolution(events, method, variable)
+		zLabel = variableLabels[variable] + " resolution (" + method.String() + ")"
+		zMax = *resLimit
+	} else {
+		counts = grid.FillPurity(events, method, plotKind)
+	}
+
+	if *histsOut != "" {
+		name := plotKind.String()
+		if plotKind == Resolution {
+			name = eicplot.HistName(name, variable.String())
+		}
+		name = eicplot.HistName(name, method.String())
+
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(name, zLabel+" vs log10(x) and log10(Q^2)", grid.H2D(grid.Z))
+		histFile.AddH2D(eicplot.HistName(name, "counts"), "events vs log10(x) and log10(Q^2)", grid.H2D(func(i, j int) float64 {
+			return float64(counts[j*grid.NBinsX+i])
+		}))
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fig := eicplot.NewHeatmapFigure(grid, moreland.ExtendedBlackBody(), 0, zMax)
+	fig.Title = *title
+	fig.XLabel = "log10(x)"
+	fig.YLabel = "log10(Q^2 / GeV^2)"
+	fig.ZLabel = zLabel
+	if err := fig.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"fmt"
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+)
+
+// BackgroundMethod selects the estimate of the combinatorial background under
+// the opposite-sign spectrum.
+type BackgroundMethod int
+
+const (
+	NoBackground BackgroundMethod = iota
+	// LikeSign uses the geometric mean of the ++ and -- pair spectra, which
+	// also accounts for correlations within the event.
+	LikeSign
+	// Mixing pairs tracks with opposite-sign tracks from earlier events of
+	// the same track multiplicity.
+	Mixing
+)
+
+var backgroundMethodNames = map[BackgroundMethod]string{
+	NoBackground: "none",
+	LikeSign:     "likesign",
+	Mixing:       "mixing",
+}
+
+func (m *BackgroundMethod) Set(valueStr string) error {
+	for method, name := range backgroundMethodNames {
+		if name == valueStr {
+			*m = method
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown background method %q", valueStr)
+}
+
+func (m *BackgroundMethod) String() string {
+	return backgroundMethodNames[*m]
+}
+
+// pairTrack is a track with the daughter mass hypothesis applied.
+type pairTrack struct {
+	P      kin.Vec4
+	Charge float32
+}
+
+// mixingPool keeps the most recent Depth events for each track multiplicity.
+type mixingPool struct {
+	Depth  int
+	events map[int][][]pairTrack
+}
+
+func newMixingPool(depth int) *mixingPool {
+	return &mixingPool{Depth: depth, events: make(map[int][][]pairTrack)}
+}
+
+// Mix fills h with the masses of opposite-sign pairs of a track from tracks
+// and one from a pooled event of the same multiplicity, then adds tracks to
+// the pool.
+func (p *mixingPool) Mix(tracks []pairTrack, h *hbook.H1D) {
+	key := len(tracks)
+	pooled := p.events[key]
+	for _, other := range pooled {
+		for _, ti := range tracks {
+			for _, tj := range other {
+				if ti.Charge*tj.Charge < 0 {
+					fillMass(h, ti.P.Add(tj.P).M())
+				}
+			}
+		}
+	}
+
+	if len(pooled) >= p.Depth {
+		pooled = pooled[1:]
+	}
+	p.events[key] = append(pooled, tracks)
+}
+
+func fillMass(h *hbook.H1D, mass float64) {
+	if mass > h.XMin() && mass < h.XMax() {
+		h.Fill(mass, 1)
+	}
+}
+
+// likeSignBackground returns 2 sqrt(n++ n--) in each bin, with the variance
+// n++ + n-- expected for counts.
+func likeSignBackground(plusPlus, minusMinus *hbook.H1D) *hbook.H1D {
+	bkg := hbook.NewH1D(plusPlus.Len(), plusPlus.XMin(), plusPlus.XMax())
+	for i := range bkg.Binning.Bins {
+		bin := &bkg.Binning.Bins[i]
+		npp, nmm := plusPlus.Binning.Bins[i].SumW(), minusMinus.Binning.Bins[i].SumW()
+		if npp <= 0 || nmm <= 0 {
+			continue
+		}
+		w := 2 * math.Sqrt(npp*nmm)
+		bin.Dist.Dist.N = plusPlus.Binning.Bins[i].Entries() + minusMinus.Binning.Bins[i].Entries()
+		bin.Dist.Dist.SumW = w
+		bin.Dist.Dist.SumW2 = npp + nmm
+		bin.Dist.SumWX = w * bin.XMid()
+		bin.Dist.SumWX2 = w * bin.XMid() * bin.XMid()
+	}
+	return bkg
+}
+
+// normalizeSidebands scales bkg to the contents of h outside the signal
+// window, and returns the scale factor.
+func normalizeSidebands(bkg, h *hbook.H1D, sigMin, sigMax float64) (float64, error) {
+	var sum, bkgSum float64
+	for i, bin := range h.Binning.Bins {
+		if x := bin.XMid(); x >= sigMin && x < sigMax {
+			continue
+		}
+		sum += bin.SumW()
+		bkgSum += bkg.Binning.Bins[i].SumW()
+	}
+	if bkgSum <= 0 {
+		return 0, fmt.Errorf("background estimate is empty in the sidebands")
+	}
+
+	scale := sum / bkgSum
+	bkg.Scale(scale)
+	return scale, nil
+}
+
+// subtract returns h minus bkg.  The uncertainty of the sideband
+// normalization is not included.
+func subtract(h, bkg *hbook.H1D) *hbook.H1D {
+	diff := hbook.NewH1D(h.Len(), h.XMin(), h.XMax())
+	eicplot.MergeH1D(diff, h)
+	neg := hbook.NewH1D(bkg.Len(), bkg.XMin(), bkg.XMax())
+	eicplot.MergeH1D(neg, bkg)
+	neg.Scale(-1)
+	eicplot.MergeH1D(diff, neg)
+	return diff
+}
+
+// SignalCount holds the signal and background in the signal window.
+type SignalCount struct {
+	S, SErr float64
+	B, BErr float64
+}
+
+func countSignal(h, bkg *hbook.H1D, sigMin, sigMax float64) SignalCount {
+	var total, totalVar, b, bVar float64
+	for i, bin := range h.Binning.Bins {
+		if x := bin.XMid(); x < sigMin || x >= sigMax {
+			continue
+		}
+		total += bin.SumW()
+		totalVar += bin.SumW2()
+		b += bkg.Binning.Bins[i].SumW()
+		bVar += bkg.Binning.Bins[i].SumW2()
+	}
+	return SignalCount{
+		S:    total - b,
+		SErr: math.Sqrt(totalVar + bVar),
+		B:    b,
+		BErr: math.Sqrt(bVar),
+	}
+}
+
+func (c SignalCount) String() string {
+	return fmt.Sprintf("S = %.1f±%.1f, B = %.1f±%.1f, S/B = %.3g", c.S, c.SErr, c.B, c.BErr, c.S/c.B)
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"math"
+	"testing"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+// countHist fills bin i of a histogram over [0, len(counts)) with counts[i]
+// unit entries.
+func countHist(counts ...int) *hbook.H1D {
+	h := hbook.NewH1D(len(counts), 0, float64(len(counts)))
+	for i, n := range counts {
+		for j := 0; j < n; j++ {
+			h.Fill(float64(i)+0.5, 1)
+		}
+	}
+	return h
+}
+
+func TestLikeSignBackground(t *testing.T) {
+	bkg := likeSignBackground(countHist(4, 0, 9), countHist(9, 5, 4))
+
+	tests := []struct {
+		sumW, sumW2 float64
+		entries     int64
+	}{
+		{12, 13, 13},
+		// no like-sign pairs of one charge leaves the bin empty
+		{0, 0, 0},
+		{12, 13, 13},
+	}
+	for i, test := range tests {
+		bin := &bkg.Binning.Bins[i]
+		if bin.SumW() != test.sumW || bin.SumW2() != test.sumW2 || bin.Entries() != test.entries {
+			t.Errorf("bin %v: sumw, sumw2, entries = %v, %v, %v, want %v, %v, %v",
+				i, bin.SumW(), bin.SumW2(), bin.Entries(), test.sumW, test.sumW2, test.entries)
+		}
+	}
+}
+
+func TestNormalizeSidebands(t *testing.T) {
+	h := countHist(10, 50, 50, 30)
+	bkg := countHist(5, 5, 5, 5)
+
+	scale, err := normalizeSidebands(bkg, h, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scale != 4 {
+		t.Errorf("scale = %v, want 4", scale)
+	}
+	for i, bin := range bkg.Binning.Bins {
+		if bin.SumW() != 20 || bin.SumW2() != 80 {
+			t.Errorf("bin %v: sumw, sumw2 = %v, %v, want 20, 80", i, bin.SumW(), bin.SumW2())
+		}
+	}
+
+	if _, err := normalizeSidebands(countHist(0, 5, 5, 0), h, 1, 3); err == nil {
+		t.Errorf("background empty in the sidebands gave no error")
+	}
+}
+
+func TestCountSignal(t *testing.T) {
+	h := countHist(10, 50, 50, 30)
+	bkg := countHist(5, 5, 5, 5)
+	bkg.Scale(4)
+
+	c := countSignal(h, bkg, 1, 3)
+	want := SignalCount{S: 60, SErr: math.Sqrt(260), B: 40, BErr: math.Sqrt(160)}
+	if math.Abs(c.S-want.S) > 1e-9 || math.Abs(c.SErr-want.SErr) > 1e-9 ||
+		math.Abs(c.B-want.B) > 1e-9 || math.Abs(c.BErr-want.BErr) > 1e-9 {
+		t.Errorf("countSignal = %v, want %v", c, want)
+	}
+}
@@ -0,0 +1,369 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+
+	"go-hep.org/x/hep/fit"
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/gonum/diff/fd"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/optimize"
+)
+
+// SignalShape selects the peak model of the mass fit.
+type SignalShape int
+
+const (
+	NoFit SignalShape = iota
+	Gauss
+	// CrystalBall has a power-law tail on the low-mass side, from radiation
+	// and energy loss of the daughters.
+	CrystalBall
+)
+
+var signalShapeNames = map[SignalShape]string{
+	NoFit:       "none",
+	Gauss:       "gauss",
+	CrystalBall: "crystalball",
+}
+
+func (s *SignalShape) Set(valueStr string) error {
+	for shape, name := range signalShapeNames {
+		if name == valueStr {
+			*s = shape
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown signal shape %q", valueStr)
+}
+
+func (s *SignalShape) String() string {
+	return signalShapeNames[*s]
+}
+
+// PeakModel is a signal peak on a polynomial background, counted per bin.
+// The parameters are the signal yield, mass and width, then alpha and n for
+// the Crystal Ball shape, then the background coefficients in powers of the
+// distance from the center of the range.
+type PeakModel struct {
+	Shape      SignalShape
+	PolyDegree int
+	BinWidth   float64
+	Center     float64
+}
+
+func (m PeakModel) nSignalParams() int {
+	if m.Shape == CrystalBall {
+		return 5
+	}
+	return 3
+}
+
+func (m PeakModel) NParams() int {
+	return m.nSignalParams() + m.PolyDegree + 1
+}
+
+func (m PeakModel) Eval(x float64, ps []float64) float64 {
+	return m.Signal(x, ps) + m.Background(x, ps)
+}
+
+func (m PeakModel) Signal(x float64, ps []float64) float64 {
+	yield, mass, width := ps[0], ps[1], math.Abs(ps[2])
+	if width == 0 {
+		return 0
+	}
+	t := (x - mass) / width
+
+	var pdf float64
+	switch m.Shape {
+	case CrystalBall:
+		alpha, n := math.Abs(ps[3]), tailPower(ps[4])
+		a := math.Pow(n/alpha, n) * math.Exp(-alpha*alpha/2)
+		b := n/alpha - alpha
+		c := n / alpha / (n - 1) * math.Exp(-alpha*alpha/2)
+		d := math.Sqrt(math.Pi/2) * (1 + math.Erf(alpha/math.Sqrt2))
+		if t > -alpha {
+			pdf = math.Exp(-t * t / 2)
+		} else {
+			pdf = a * math.Pow(b-t, -n)
+		}
+		pdf /= width * (c + d)
+	default:
+		pdf = math.Exp(-t*t/2) / (width * math.Sqrt(2*math.Pi))
+	}
+	return yield * m.BinWidth * pdf
+}
+
+// minTailPower bounds the Crystal Ball n away from 1, where the tail
+// integral diverges and the normalized signal vanishes.
+const minTailPower = 1.01
+
+func tailPower(p float64) float64 {
+	return minTailPower + math.Abs(p-minTailPower)
+}
+
+func (m PeakModel) Background(x float64, ps []float64) float64 {
+	var bkg, pow float64 = 0, 1
+	for _, c := range ps[m.nSignalParams():] {
+		bkg += c * pow
+		pow *= x - m.Center
+	}
+	return bkg
+}
+
+// PeakFit holds the fitted parameters with their uncertainties from the
+// inverse Hessian of the chi-square.  EDM is the decrease in chi-square
+// still expected from the gradient at the result, which is NaN if the
+// Hessian is not positive definite.
+type PeakFit struct {
+	Model  PeakModel
+	Params []float64
+	Errors []float64
+	Chi2   float64
+	NDF    int
+	EDM    float64
+}
+
+// FitPeak fits the model to the bins of h with entries, starting the peak at
+// the highest bin.
+func FitPeak(h *hbook.H1D, model PeakModel) (*PeakFit, error) {
+	bins := h.Binning.Bins
+	model.BinWidth = bins[0].XWidth()
+	model.Center = (h.XMin() + h.XMax()) / 2
+
+	ps := make([]float64, model.NParams())
+	var sum, maxContent float64
+	for _, bin := range bins {
+		sum += bin.SumW()
+		if bin.SumW() > maxContent {
+			maxContent = bin.SumW()
+			ps[1] = bin.XMid()
+		}
+	}
+	edge := (bins[0].SumW() + bins[len(bins)-1].SumW()) / 2
+	ps[0] = math.Max(sum-edge*float64(len(bins)), sum/2)
+	ps[2] = (h.XMax() - h.XMin()) / 20
+	if model.Shape == CrystalBall {
+		ps[3], ps[4] = 1.5, 2
+	}
+	ps[model.nSignalParams()] = edge
+
+	f := fit.Func1D{F: model.Eval, Ps: ps}
+	var xs, ys, errs []float64
+	for _, bin := range bins {
+		if bin.Entries() <= 0 {
+			continue
+		}
+		xs = append(xs, bin.XMid())
+		ys = append(ys, bin.SumW())
+		errs = append(errs, bin.ErrW())
+	}
+	f.X, f.Y, f.Err = xs, ys, errs
+
+	// the simplex is robust to the rough starting point but stops short of
+	// the minimum, which BFGS then finds.  BFGS usually ends in a failed
+	// line search once the numerical gradient is dominated by rounding, so
+	// convergence is judged from the result instead of the returned error.
+	res, err := fit.Curve1D(f, nil, &optimize.NelderMead{})
+	if err != nil {
+		return nil, err
+	}
+	f.Ps = res.X
+	res, err = fit.Curve1D(f, nil, &optimize.BFGS{})
+	if res == nil {
+		return nil, err
+	}
+	ps = res.X
+
+	halfChi2 := func(ps []float64) float64 {
+		var chi2 float64
+		for i := range xs {
+			res := (model.Eval(xs[i], ps) - ys[i]) / errs[i]
+			chi2 += res * res
+		}
+		return chi2 / 2
+	}
+
+	result := &PeakFit{
+		Model:  model,
+		Params: ps,
+		Errors: make([]float64, len(ps)),
+		Chi2:   2 * halfChi2(ps),
+		NDF:    len(xs) - len(ps),
+		EDM:    math.NaN(),
+	}
+	for i := range result.Errors {
+		result.Errors[i] = math.NaN()
+	}
+
+	grad := make([]float64, len(ps))
+	fd.Gradient(grad, halfChi2, ps, nil)
+	hess := mat.NewSymDense(len(ps), nil)
+	fd.Hessian(hess, halfChi2, ps, nil)
+	indices := allIndices(len(ps))
+	cov, ok := covariance(hess, indices)
+	if !ok && model.Shape == CrystalBall {
+		// the tail is often unconstrained by a narrow peak, so quote the
+		// other uncertainties with the tail shape held fixed
+		indices = nil
+		for i := range ps {
+			if i != 3 && i != 4 {
+				indices = append(indices, i)
+			}
+		}
+		cov, ok = covariance(hess, indices)
+	}
+	if ok {
+		result.EDM = 0
+		for i, iParam := range indices {
+			for j, jParam := range indices {
+				result.EDM += grad[iParam] * cov.At(i, j) * grad[jParam]
+			}
+		}
+		// uncertainties are only quoted at the minimum
+		if result.Converged() {
+			for i, iParam := range indices {
+				result.Errors[iParam] = math.Sqrt(cov.At(i, i))
+			}
+		}
+	}
+
+	// the shape parameters enter the model symmetrically, so report their
+	// canonical values
+	result.Params[2] = math.Abs(result.Params[2])
+	if model.Shape == CrystalBall {
+		result.Params[3] = math.Abs(result.Params[3])
+		result.Params[4] = tailPower(result.Params[4])
+	}
+	return result, nil
+}
+
+// maxEDM is the largest estimated distance to the minimum, in chi-square,
+// of a converged fit.  It is well below the change of 1 that sets the
+// uncertainties, and allows for flat directions such as an unconstrained
+// tail.
+const maxEDM = 0.01
+
+// Converged reports whether the fit reached a minimum of the chi-square with
+// a positive definite Hessian.
+func (f *PeakFit) Converged() bool {
+	return f.EDM < maxEDM
+}
+
+// covariance returns the inverse of the submatrix of hess at indices, if it
+// is positive definite.
+func covariance(hess *mat.SymDense, indices []int) (*mat.SymDense, bool) {
+	sub := mat.NewSymDense(len(indices), nil)
+	for i, iParam := range indices {
+		for j, jParam := range indices[i:] {
+			sub.SetSym(i, i+j, hess.At(iParam, jParam))
+		}
+	}
+
+	var chol mat.Cholesky
+	cov := mat.NewSymDense(len(indices), nil)
+	if !chol.Factorize(sub) || chol.InverseTo(cov) != nil {
+		return nil, false
+	}
+	return cov, true
+}
+
+func allIndices(n int) []int {
+	indices := make([]int, n)
+	for i := range indices {
+		indices[i] = i
+	}
+	return indices
+}
+
+func (f *PeakFit) Yield() (float64, float64) { return f.Params[0], f.Errors[0] }
+func (f *PeakFit) Mass() (float64, float64)  { return f.Params[1], f.Errors[1] }
+func (f *PeakFit) Width() (float64, float64) { return f.Params[2], f.Errors[2] }
+
+func (f *PeakFit) Eval(x float64) float64 {
+	return f.Model.Eval(x, f.Params)
+}
+
+// Summary gives the fitted mass, width and yield for legends.
+func (f *PeakFit) Summary() string {
+	mass, massErr := f.Mass()
+	width, widthErr := f.Width()
+	yield, yieldErr := f.Yield()
+	return fmt.Sprintf("m = %.4f±%.4f, σ = %.4f±%.4f, N = %.0f±%.0f", mass, massErr, width, widthErr, yield, yieldErr)
+}
+
+// fitRecord is the machine-readable form of a PeakFit.
+type fitRecord struct {
+	File        string    `json:"file"`
+	Shape       string    `json:"shape"`
+	Mass        float64   `json:"mass"`
+	MassErr     float64   `json:"mass_err"`
+	Width       float64   `json:"width"`
+	WidthErr    float64   `json:"width_err"`
+	Yield       float64   `json:"yield"`
+	YieldErr    float64   `json:"yield_err"`
+	Chi2        float64   `json:"chi2"`
+	NDF         int       `json:"ndf"`
+	EDM         float64   `json:"edm"`
+	Converged   bool      `json:"converged"`
+	Params      []float64 `json:"params"`
+	ParamErrors []float64 `json:"param_errs"`
+}
+
+func newFitRecord(filename string, f *PeakFit) fitRecord {
+	r := fitRecord{
+		File:        filename,
+		Shape:       f.Model.Shape.String(),
+		Chi2:        f.Chi2,
+		NDF:         f.NDF,
+		EDM:         f.EDM,
+		Converged:   f.Converged(),
+		Params:      f.Params,
+		ParamErrors: f.Errors,
+	}
+	r.Mass, r.MassErr = f.Mass()
+	r.Width, r.WidthErr = f.Width()
+	r.Yield, r.YieldErr = f.Yield()
+	return r
+}
+
+// writeFitRecords writes one JSON object per line to filename, or to stdout
+// if filename is empty.  JSON has no NaN, so failed uncertainties and EDM
+// are written as -1.
+func writeFitRecords(records []fitRecord, filename string) error {
+	var w io.Writer = os.Stdout
+	if filename != "" {
+		file, err := os.Create(filename)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		w = file
+	}
+
+	enc := json.NewEncoder(w)
+	for _, r := range records {
+		r.MassErr, r.WidthErr, r.YieldErr = finite(r.MassErr), finite(r.WidthErr), finite(r.YieldErr)
+		r.EDM = finite(r.EDM)
+		errs := make([]float64, len(r.ParamErrors))
+		for i, e := range r.ParamErrors {
+			errs[i] = finite(e)
+		}
+		r.ParamErrors = errs
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func finite(x float64) float64 {
+	if math.IsNaN(x) || math.IsInf(x, 0) {
+		return -1
+	}
+	return x
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+func TestCrystalBallNormalization(t *testing.T) {
+	const dt = 2e-3
+	// the tail is integrated far enough out for its remainder to be
+	// negligible at these powers
+	tests := []struct {
+		alpha, n float64
+	}{
+		{0.5, 3},
+		{1, 3},
+		{1.5, 2.5},
+		{2, 5},
+	}
+
+	for _, test := range tests {
+		model := PeakModel{Shape: CrystalBall, BinWidth: dt}
+		ps := []float64{1, 0, 1, test.alpha, test.n, 0}
+		var sum float64
+		for x := -2000 + dt/2; x < 20; x += dt {
+			sum += model.Signal(x, ps)
+		}
+		if math.Abs(sum-1) > 1e-3 {
+			t.Errorf("alpha %v, n %v: integral = %v, want 1", test.alpha, test.n, sum)
+		}
+	}
+}
+
+// peakHist has a Gaussian peak of nSig entries over nBkg flat entries.
+func peakHist(seed int64, nSig, nBkg int, mass, width float64) *hbook.H1D {
+	rnd := rand.New(rand.NewSource(seed))
+	h := hbook.NewH1D(50, 2.9, 3.3)
+	for i := 0; i < nSig; i++ {
+		h.Fill(mass+width*rnd.NormFloat64(), 1)
+	}
+	for i := 0; i < nBkg; i++ {
+		h.Fill(2.9+0.4*rnd.Float64(), 1)
+	}
+	return h
+}
+
+func chi2At(h *hbook.H1D, model PeakModel, ps []float64) float64 {
+	model.BinWidth = h.Binning.Bins[0].XWidth()
+	model.Center = (h.XMin() + h.XMax()) / 2
+	var chi2 float64
+	for _, bin := range h.Binning.Bins {
+		if bin.Entries() <= 0 {
+			continue
+		}
+		res := (model.Eval(bin.XMid(), ps) - bin.SumW()) / bin.ErrW()
+		chi2 += res * res
+	}
+	return chi2
+}
+
+func TestFitPeak(t *testing.T) {
+	const mass, width = 3.097, 0.02
+	tests := []struct {
+		name       string
+		model      PeakModel
+		seed       int64
+		nSig, nBkg int
+	}{
+		{"gauss", PeakModel{Shape: Gauss, PolyDegree: 1}, 2, 2000, 2000},
+		{"gauss flat", PeakModel{Shape: Gauss}, 3, 1000, 4000},
+		{"crystal ball", PeakModel{Shape: CrystalBall, PolyDegree: 1}, 4, 2000, 2000},
+	}
+
+	for _, test := range tests {
+		h := peakHist(test.seed, test.nSig, test.nBkg, mass, width)
+		truth := make([]float64, test.model.NParams())
+		truth[0], truth[1], truth[2] = float64(test.nSig), mass, width
+		if test.model.Shape == CrystalBall {
+			// a Gaussian peak has its tail far out
+			truth[3], truth[4] = 5, 2
+		}
+		truth[test.model.nSignalParams()] = float64(test.nBkg) / 50
+
+		fit, err := FitPeak(h, test.model)
+		if err != nil {
+			t.Errorf("%v: %v", test.name, err)
+			continue
+		}
+		if !fit.Converged() {
+			t.Errorf("%v: not converged, EDM = %v", test.name, fit.EDM)
+		}
+		if truthChi2 := chi2At(h, test.model, truth); fit.Chi2 > truthChi2 {
+			t.Errorf("%v: chi2 = %v, above %v at the true parameters", test.name, fit.Chi2, truthChi2)
+		}
+		if fit.NDF != 50-test.model.NParams() {
+			t.Errorf("%v: NDF = %v, want %v", test.name, fit.NDF, 50-test.model.NParams())
+		}
+
+		for i, name := range []string{"yield", "mass", "width"} {
+			if math.IsNaN(fit.Errors[i]) || math.Abs(fit.Params[i]-truth[i]) > 4*fit.Errors[i] {
+				t.Errorf("%v: %v = %v±%v, want %v", test.name, name, fit.Params[i], fit.Errors[i], truth[i])
+			}
+		}
+	}
+}
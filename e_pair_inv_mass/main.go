@@ -3,9 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/color"
 	"log"
-	"math"
 	"os"
 
 	"github.com/proio-org/go-proio"
@@ -13,13 +11,16 @@ import (
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
 
 options:
 `,
@@ -29,15 +30,81 @@ options:
 
 func main() {
 	var (
-		title  = flag.String("title", "", "plot title")
-		output = flag.String("output", "out.png", "output file")
+		title    = flag.String("title", "", "plot title")
+		output   = flag.String("output", "out.png", "output file")
+		nWorkers = eicplot.NewFlagWorkers()
+		histsOut = eicplot.NewFlagHistFile()
+		labels   = eicplot.NewFlagLabels()
+		legend   = eicplot.NewFlagLegend()
+
+		ratio = eicplot.NewFlagRatio()
+		ref   = eicplot.NewFlagReference()
+
+		fitPoly = flag.Int("fitpoly", 1, "degree of the polynomial background in the fit")
+		fitOut  = flag.String("fitout", "", "file to write fit results to as JSON lines (default stdout, with other output on stderr)")
+
+		preset  = flag.String("resonance", "jpsi", "preset daughters and mass window (jpsi, phi, k0s or upsilon)")
+		nBins   = flag.Int("nbins", 0, "number of bins (overrides the preset)")
+		massMin = flag.Float64("massmin", 0, "minimum pair mass (overrides the preset)")
+		massMax = flag.Float64("massmax", 0, "maximum pair mass (overrides the preset)")
+		sigMin  = flag.Float64("signalmin", 0, "minimum mass of the signal window (overrides the preset)")
+		sigMax  = flag.Float64("signalmax", 0, "maximum mass of the signal window (overrides the preset)")
+
+		subtractBkg = flag.Bool("subtract", false, "subtract the background estimate instead of overlaying it")
+		mixDepth    = flag.Int("mixdepth", 5, "number of events of each multiplicity kept for mixing")
+
+		fitShape  SignalShape
+		daughter  Daughter
+		bkgMethod BackgroundMethod
 	)
+	flag.Var(&bkgMethod, "bkg", "combinatorial background estimate (none, likesign or mixing)")
+	flag.Var(&fitShape, "fit", "signal shape to fit (none, gauss or crystalball)")
+	flag.Var(&daughter, "daughter", "daughter mass hypothesis (e, mu, pi, K or p; overrides the preset)")
 	flag.Usage = printUsage
 	flag.Parse()
 	if flag.NArg() < 1 {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
+	if *ratio && (*ref < 0 || *ref >= flag.NArg()) {
+		log.Fatal("Reference is not an input file index")
+	}
+	if *fitPoly < 0 {
+		log.Fatal("Background polynomial degree must not be negative")
+	}
+
+	res, ok := resonancePresets[*preset]
+	if !ok {
+		log.Fatalf("Unknown resonance preset %q", *preset)
+	}
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "daughter":
+			res.Daughter = daughter
+		case "nbins":
+			res.NBins = *nBins
+		case "massmin":
+			res.MassMin = *massMin
+		case "massmax":
+			res.MassMax = *massMax
+		case "signalmin":
+			res.SignalMin = *sigMin
+		case "signalmax":
+			res.SignalMax = *sigMax
+		}
+	})
+	if res.NBins < 1 || res.MassMax <= res.MassMin {
+		log.Fatal("Invalid mass binning")
+	}
+	if bkgMethod != NoBackground && (res.SignalMax <= res.SignalMin || res.SignalMin < res.MassMin || res.SignalMax > res.MassMax) {
+		log.Fatal("Signal window must lie within the mass range")
+	}
+	if *mixDepth < 1 {
+		log.Fatal("Mixing depth must be positive")
+	}
+	if *subtractBkg && bkgMethod == NoBackground {
+		log.Fatal("-subtract needs a background estimate")
+	}
 
 	p, _ := plot.New()
 	p.Title.Text = *title
@@ -45,75 +112,167 @@ func main() {
 	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
 	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
 
+	histFile := &eicplot.HistFile{}
+	var series []plotutil.ErrorPoints
+	var fitRecords []fitRecord
 	for i, filename := range flag.Args() {
-		hist := makeInvMassHist(filename)
-
-		lineColor := color.RGBA{A: 255}
-		switch i {
-		case 1:
-			lineColor = color.RGBA{G: 255, A: 255}
-		case 2:
-			lineColor = color.RGBA{B: 255, A: 255}
-		case 3:
-			lineColor = color.RGBA{R: 255, B: 127, G: 127, A: 255}
+		hists := makeInvMassHists(filename, res, bkgMethod == Mixing, *mixDepth, *nWorkers)
+		tag := eicplot.FileTag(filename)
+		histFile.AddH1D(eicplot.HistName("invmass_opposite_sign", tag), "opposite-sign pair mass", hists.Opposite)
+		histFile.AddH1D(eicplot.HistName("invmass_plus_plus", tag), "++ pair mass", hists.PlusPlus)
+		histFile.AddH1D(eicplot.HistName("invmass_minus_minus", tag), "-- pair mass", hists.MinusMinus)
+		if bkgMethod == Mixing {
+			histFile.AddH1D(eicplot.HistName("invmass_mixed", tag), "mixed-event opposite-sign pair mass", hists.Mixed)
 		}
 
+		hist := hists.Opposite
+		var bkg *hbook.H1D
+		if bkgMethod != NoBackground {
+			if bkgMethod == LikeSign {
+				bkg = likeSignBackground(hists.PlusPlus, hists.MinusMinus)
+			} else {
+				// normalize a copy, keeping the saved mixed spectrum raw
+				bkg = hbook.NewH1D(hists.Mixed.Len(), hists.Mixed.XMin(), hists.Mixed.XMax())
+				eicplot.MergeH1D(bkg, hists.Mixed)
+			}
+			if _, err := normalizeSidebands(bkg, hist, res.SignalMin, res.SignalMax); err != nil {
+				log.Fatalf("%v: %v", filename, err)
+			}
+			histFile.AddH1D(eicplot.HistName("invmass_background", tag), bkgMethod.String()+" background, normalized in the sidebands", bkg)
+
+			count := countSignal(hist, bkg, res.SignalMin, res.SignalMax)
+			// stdout is kept for the fit results
+			fmt.Fprintf(os.Stderr, "%v: %v in [%v, %v)\n", eicplot.Label(labels, i, tag), count, res.SignalMin, res.SignalMax)
+
+			if *subtractBkg {
+				hist = subtract(hist, bkg)
+				histFile.AddH1D(eicplot.HistName("invmass_subtracted", tag), "background-subtracted pair mass", hist)
+			}
+		}
+
+		series = append(series, eicplot.H1DErrorPoints(hist))
+
 		h := hplot.NewH1D(hist)
-		h.LineStyle.Color = lineColor
-		if len(flag.Args()) == 1 {
+		h.LineStyle = eicplot.Style(i).LineStyle()
+		if len(flag.Args()) == 1 && fitShape == NoFit {
 			h.Infos.Style = hplot.HInfoSummary
 		}
 
 		p.Add(h)
+
+		label := eicplot.Label(labels, i, tag)
+		if fitShape != NoFit {
+			peakFit, err := FitPeak(hist, PeakModel{Shape: fitShape, PolyDegree: *fitPoly})
+			if err != nil {
+				log.Fatal(err)
+			}
+			if !peakFit.Converged() {
+				log.Printf("%v: fit did not converge (EDM = %.3g); uncertainties are not quoted", filename, peakFit.EDM)
+			}
+			fitRecords = append(fitRecords, newFitRecord(filename, peakFit))
+
+			fitLine := plotter.NewFunction(peakFit.Eval)
+			fitLine.LineStyle = eicplot.Style(i).LineStyle()
+			fitLine.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+			fitLine.Samples = 200
+			p.Add(fitLine)
+
+			label += " (" + peakFit.Summary() + ")"
+		}
+		legend.Add(p, label, h)
+
+		if bkg != nil && !*subtractBkg {
+			b := hplot.NewH1D(bkg)
+			b.LineStyle = eicplot.Style(i).LineStyle()
+			b.LineStyle.Dashes = []vg.Length{vg.Points(1), vg.Points(2)}
+			p.Add(b)
+			legend.Add(p, eicplot.Label(labels, i, tag)+" "+bkgMethod.String()+" background", b)
+		}
+	}
+
+	if fitShape != NoFit {
+		if err := writeFitRecords(fitRecords, *fitOut); err != nil {
+			log.Fatal(err)
+		}
 	}
 
-	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	if *ratio {
+		fig := eicplot.NewRatioFigure(p)
+		fig.Ratio.Y.Label.Text = "ratio to " + eicplot.FileTag(flag.Arg(*ref))
+		fig.AddFileRatios(series, 1, *ref)
+		if err := fig.Save(6*vg.Inch, 5*vg.Inch, *output); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	}
+
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
-func makeInvMassHist(filename string) *hbook.H1D {
-	invMassHist := hbook.NewH1D(50, 2.9, 3.3)
+// pairHists holds the pair mass spectra of one input file.  Mixed is only
+// filled when event mixing is enabled.
+type pairHists struct {
+	Opposite, PlusPlus, MinusMinus, Mixed *hbook.H1D
+}
 
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+func makeInvMassHists(filename string, res Resonance, mix bool, mixDepth, nWorkers int) pairHists {
+	workerHists := make([][]*hbook.H1D, nWorkers)
+	for i := range workerHists {
+		for j := 0; j < 3; j++ {
+			workerHists[i] = append(workerHists[i], hbook.NewH1D(res.NBins, res.MassMin, res.MassMax))
+		}
 	}
+	daughterMass := res.Daughter.Mass()
+
+	// the pool is fed in file order, so that the mixed background does not
+	// depend on the number of workers
+	mixed := hbook.NewH1D(res.NBins, res.MassMin, res.MassMax)
+	pool := newMixingPool(mixDepth)
+	process := func(worker int, event *proio.Event) interface{} {
+		hists := workerHists[worker]
 
-	for event := range reader.ScanEvents() {
 		ids := event.TaggedEntries("Reconstructed")
 
-		tracks := []*eic.Track{}
+		tracks := []pairTrack{}
 		for _, id := range ids {
 			track, ok := event.GetEntry(id).(*eic.Track)
 			if ok && len(track.Segment) > 0 {
-				tracks = append(tracks, track)
+				tracks = append(tracks, pairTrack{
+					P:      kin.FromTrackSegment(track.Segment[0], daughterMass),
+					Charge: *track.Segment[0].Chargesign,
+				})
 			}
 		}
 
 		for i := 0; i < len(tracks); i++ {
 			for j := i + 1; j < len(tracks); j++ {
-				if (*tracks[i].Segment[0].Chargesign)*(*tracks[j].Segment[0].Chargesign) > 0 {
-					continue
-				}
-
-				poqi := tracks[i].Segment[0].Poq
-				poqj := tracks[j].Segment[0].Poq
-				p := []float64{*poqi.X + *poqj.X, *poqi.Y + *poqj.Y, *poqi.Z + *poqj.Z}
-
-				p2 := math.Pow(p[0], 2) + math.Pow(p[1], 2) + math.Pow(p[2], 2)
-				Ei := math.Sqrt(math.Pow(*poqi.X, 2) + math.Pow(*poqi.Y, 2) + math.Pow(*poqi.Z, 2))
-				Ej := math.Sqrt(math.Pow(*poqj.X, 2) + math.Pow(*poqj.Y, 2) + math.Pow(*poqj.Z, 2))
-				E2 := math.Pow(Ei+Ej, 2)
-
-				// eta := math.Atanh(p[2] / math.Sqrt(p2))
-				invMass := math.Sqrt(E2 - p2)
-
-				if invMass > 2.9 && invMass < 3.3 {
-					invMassHist.Fill(invMass, 1)
+				h := hists[0]
+				switch {
+				case tracks[i].Charge > 0 && tracks[j].Charge > 0:
+					h = hists[1]
+				case tracks[i].Charge < 0 && tracks[j].Charge < 0:
+					h = hists[2]
 				}
+				fillMass(h, tracks[i].P.Add(tracks[j].P).M())
 			}
 		}
+
+		return tracks
+	}
+	mixTracks := func(result interface{}) {
+		if mix {
+			pool.Mix(result.([]pairTrack), mixed)
+		}
+	}
+	if err := eicplot.ProcessOrderedEvents([]string{filename}, nWorkers, process, mixTracks); err != nil {
+		log.Fatal(err)
 	}
 
-	reader.Close()
-	return invMassHist
+	hists := eicplot.MergeH1DSets(workerHists)
+	return pairHists{Opposite: hists[0], PlusPlus: hists[1], MinusMinus: hists[2], Mixed: mixed}
 }
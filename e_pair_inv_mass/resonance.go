@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/decibelcooper/eicplot/kin"
+)
+
+// Daughter is the mass hypothesis assigned to both tracks of a pair.
+type Daughter int
+
+const (
+	Electron Daughter = iota
+	Muon
+	Pion
+	Kaon
+	Proton
+)
+
+var daughterNames = map[Daughter]string{
+	Electron: "e",
+	Muon:     "mu",
+	Pion:     "pi",
+	Kaon:     "K",
+	Proton:   "p",
+}
+
+var daughterMasses = map[Daughter]float64{
+	Electron: kin.ElectronMass,
+	Muon:     kin.MuonMass,
+	Pion:     kin.PionMass,
+	Kaon:     kin.KaonMass,
+	Proton:   kin.ProtonMass,
+}
+
+func (d *Daughter) Set(valueStr string) error {
+	for daughter, name := range daughterNames {
+		if name == valueStr {
+			*d = daughter
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown daughter %q", valueStr)
+}
+
+func (d *Daughter) String() string {
+	return daughterNames[*d]
+}
+
+func (d Daughter) Mass() float64 {
+	return daughterMasses[d]
+}
+
+// Resonance sets the daughter hypothesis and the mass window of the pair mass
+// histogram.  The signal window separates the peak from the sidebands used to
+// normalize background estimates.
+type Resonance struct {
+	Daughter             Daughter
+	NBins                int
+	MassMin, MassMax     float64
+	SignalMin, SignalMax float64
+}
+
+var resonancePresets = map[string]Resonance{
+	"jpsi":    {Daughter: Electron, NBins: 50, MassMin: 2.9, MassMax: 3.3, SignalMin: 3.0, SignalMax: 3.2},
+	"phi":     {Daughter: Kaon, NBins: 60, MassMin: 0.99, MassMax: 1.05, SignalMin: 1.01, SignalMax: 1.03},
+	"k0s":     {Daughter: Pion, NBins: 50, MassMin: 0.45, MassMax: 0.55, SignalMin: 0.48, SignalMax: 0.52},
+	"upsilon": {Daughter: Electron, NBins: 50, MassMin: 8.5, MassMax: 11, SignalMin: 9.2, SignalMax: 10.6},
+}
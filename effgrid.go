@@ -0,0 +1,174 @@
+package eicplot
+
+import (
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/plot/plotter"
+)
+
+// EfficiencyGrid counts passing and total entries in bins of two variables.
+// It draws as a heatmap of the efficiency, and projects onto either variable
+// as an Efficiency.
+type EfficiencyGrid struct {
+	Pass, Total *hbook.H2D
+	Interval    IntervalMethod
+	CL          float64
+
+	// MinTotal masks cells with fewer total entries.
+	MinTotal float64
+}
+
+func NewEfficiencyGrid(nBinsX int, xLow, xHigh float64, nBinsY int, yLow, yHigh float64) *EfficiencyGrid {
+	return &EfficiencyGrid{
+		Pass:  hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
+		Total: hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
+		CL:    OneSigma,
+	}
+}
+
+// Fill counts an entry at x, y, unless it is out of range.
+func (g *EfficiencyGrid) Fill(x, y float64, pass bool) {
+	if x < g.Total.XMin() || x >= g.Total.XMax() || y < g.Total.YMin() || y >= g.Total.YMax() {
+		return
+	}
+	g.Total.Fill(x, y, 1)
+	if pass {
+		g.Pass.Fill(x, y, 1)
+	}
+}
+
+// Merge adds the entries of other, which must have identical binning.
+func (g *EfficiencyGrid) Merge(other *EfficiencyGrid) {
+	MergeH2D(g.Pass, other.Pass)
+	MergeH2D(g.Total, other.Total)
+}
+
+// ProjectX returns the efficiency vs x, summed over y.
+func (g *EfficiencyGrid) ProjectX() *Efficiency {
+	nx, ny := g.Dims()
+	eff := NewEfficiency(nx, g.Total.XMin(), g.Total.XMax())
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			addCell(eff.Pass, i, &g.cell(g.Pass, i, j).X)
+			addCell(eff.Total, i, &g.cell(g.Total, i, j).X)
+		}
+	}
+	return eff
+}
+
+// ProjectY returns the efficiency vs y, summed over x.
+func (g *EfficiencyGrid) ProjectY() *Efficiency {
+	nx, ny := g.Dims()
+	eff := NewEfficiency(ny, g.Total.YMin(), g.Total.YMax())
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			addCell(eff.Pass, j, &g.cell(g.Pass, i, j).Y)
+			addCell(eff.Total, j, &g.cell(g.Total, i, j).Y)
+		}
+	}
+	return eff
+}
+
+// SliceY returns the efficiency vs y in column i.
+func (g *EfficiencyGrid) SliceY(i int) *Efficiency {
+	_, ny := g.Dims()
+	eff := NewEfficiency(ny, g.Total.YMin(), g.Total.YMax())
+	eff.Interval, eff.CL = g.Interval, g.CL
+	for j := 0; j < ny; j++ {
+		addCell(eff.Pass, j, &g.cell(g.Pass, i, j).Y)
+		addCell(eff.Total, j, &g.cell(g.Total, i, j).Y)
+	}
+	return eff
+}
+
+// XRange returns the edges of column i.
+func (g *EfficiencyGrid) XRange(i int) (low, high float64) {
+	nx, _ := g.Dims()
+	width := (g.Total.XMax() - g.Total.XMin()) / float64(nx)
+	return g.Total.XMin() + float64(i)*width, g.Total.XMin() + float64(i+1)*width
+}
+
+// addCell adds the moments of a cell along one axis to bin i of h, keeping
+// the entries and sums of squared weights of the original fills.
+func addCell(h *hbook.H1D, i int, d *hbook.Dist1D) {
+	addDist1D(&h.Binning.Bins[i].Dist, d)
+	addDist1D(&h.Binning.Dist, d)
+}
+
+func (g *EfficiencyGrid) cell(h *hbook.H2D, i, j int) *hbook.Dist2D {
+	return &h.Binning.Bins[j*h.Binning.Nx+i].Dist
+}
+
+func (g *EfficiencyGrid) count(h *hbook.H2D, i, j int) float64 {
+	return h.Binning.Bins[j*h.Binning.Nx+i].SumW()
+}
+
+// H2D returns a histogram with the content of each cell set to Z, leaving out
+// masked cells.
+func (g *EfficiencyGrid) H2D() *hbook.H2D {
+	return g.gridH2D(g)
+}
+
+// Bounds returns the interval on the efficiency in cell i, j.
+func (g *EfficiencyGrid) Bounds(i, j int) (low, high float64) {
+	return BinomialInterval(g.Interval, g.count(g.Pass, i, j), g.count(g.Total, i, j), g.CL)
+}
+
+// Uncertainties returns a grid of the half widths of the intervals on the
+// efficiency, with the same mask.
+func (g *EfficiencyGrid) Uncertainties() plotter.GridXYZ {
+	return effUncertaintyGrid{g}
+}
+
+// UncertaintyH2D returns a histogram with the content of each cell set to
+// the half width of the interval, leaving out masked cells.
+func (g *EfficiencyGrid) UncertaintyH2D() *hbook.H2D {
+	return g.gridH2D(g.Uncertainties())
+}
+
+func (g *EfficiencyGrid) gridH2D(grid plotter.GridXYZ) *hbook.H2D {
+	nx, ny := g.Dims()
+	h := hbook.NewH2D(nx, g.Total.XMin(), g.Total.XMax(), ny, g.Total.YMin(), g.Total.YMax())
+	for i := 0; i < nx; i++ {
+		for j := 0; j < ny; j++ {
+			if z := grid.Z(i, j); !math.IsNaN(z) {
+				h.Fill(g.X(i), g.Y(j), z)
+			}
+		}
+	}
+	return h
+}
+
+func (g *EfficiencyGrid) Dims() (int, int) {
+	return g.Total.Binning.Nx, g.Total.Binning.Ny
+}
+
+// Z is the efficiency, or NaN for empty or masked cells.
+func (g *EfficiencyGrid) Z(i, j int) float64 {
+	total := g.count(g.Total, i, j)
+	if total <= 0 || total < g.MinTotal {
+		return math.NaN()
+	}
+	return g.count(g.Pass, i, j) / total
+}
+
+func (g *EfficiencyGrid) X(i int) float64 {
+	return g.Total.GridXYZ().X(i)
+}
+
+func (g *EfficiencyGrid) Y(j int) float64 {
+	return g.Total.GridXYZ().Y(j)
+}
+
+type effUncertaintyGrid struct {
+	*EfficiencyGrid
+}
+
+func (g effUncertaintyGrid) Z(i, j int) float64 {
+	if math.IsNaN(g.EfficiencyGrid.Z(i, j)) {
+		return math.NaN()
+	}
+	low, high := g.Bounds(i, j)
+	return (high - low) / 2
+}
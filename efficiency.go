@@ -0,0 +1,171 @@
+package eicplot
+
+import (
+	"fmt"
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/gonum/mathext"
+	"gonum.org/v1/gonum/stat/distuv"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+)
+
+// OneSigma is the confidence level of a Gaussian one standard deviation
+// interval.
+const OneSigma = 0.682689492
+
+type IntervalMethod int
+
+const (
+	ClopperPearson IntervalMethod = iota
+	Wilson
+	// Bayesian gives the central posterior interval for a uniform prior,
+	// or the one-sided interval when no or all entries pass.
+	Bayesian
+)
+
+var intervalMethodNames = map[IntervalMethod]string{
+	ClopperPearson: "clopper-pearson",
+	Wilson:         "wilson",
+	Bayesian:       "bayesian",
+}
+
+func (m *IntervalMethod) Set(valueStr string) error {
+	for method, name := range intervalMethodNames {
+		if name == valueStr {
+			*m = method
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown interval method %q", valueStr)
+}
+
+func (m *IntervalMethod) String() string {
+	return intervalMethodNames[*m]
+}
+
+// BinomialInterval returns the bounds of the interval on the efficiency for k
+// passing out of n total at confidence level cl.
+func BinomialInterval(method IntervalMethod, k, n, cl float64) (low, high float64) {
+	if n <= 0 {
+		return 0, 1
+	}
+	k = math.Min(math.Max(k, 0), n)
+	alpha := 1 - cl
+
+	switch method {
+	case Wilson:
+		z := distuv.UnitNormal.Quantile(1 - alpha/2)
+		z2 := z * z
+		center := (k + z2/2) / (n + z2)
+		halfWidth := z / (n + z2) * math.Sqrt(k*(n-k)/n+z2/4)
+		return math.Max(center-halfWidth, 0), math.Min(center+halfWidth, 1)
+	case Bayesian:
+		a, b := k+1, n-k+1
+		switch {
+		case k == 0:
+			return 0, mathext.InvRegIncBeta(a, b, cl)
+		case k == n:
+			return mathext.InvRegIncBeta(a, b, alpha), 1
+		}
+		return mathext.InvRegIncBeta(a, b, alpha/2), mathext.InvRegIncBeta(a, b, 1-alpha/2)
+	default:
+		low, high = 0, 1
+		if k > 0 {
+			low = mathext.InvRegIncBeta(k, n-k+1, alpha/2)
+		}
+		if k < n {
+			high = mathext.InvRegIncBeta(k+1, n-k, 1-alpha/2)
+		}
+		return low, high
+	}
+}
+
+// Efficiency holds histograms of passing and total entries.  The passing
+// histogram is filled independently, so it is not required to be a subset
+// of the total.
+type Efficiency struct {
+	Pass, Total *hbook.H1D
+	Interval    IntervalMethod
+	CL          float64
+}
+
+func NewEfficiency(nBins int, xMin, xMax float64) *Efficiency {
+	return &Efficiency{
+		Pass:  hbook.NewH1D(nBins, xMin, xMax),
+		Total: hbook.NewH1D(nBins, xMin, xMax),
+		CL:    OneSigma,
+	}
+}
+
+// Merge adds the entries of other, which must have identical binning.
+func (e *Efficiency) Merge(other *Efficiency) {
+	MergeH1D(e.Pass, other.Pass)
+	MergeH1D(e.Total, other.Total)
+}
+
+func (e *Efficiency) Len() int {
+	return e.Total.Len()
+}
+
+// Value returns the efficiency in bin i, or 0 if the bin is empty.
+func (e *Efficiency) Value(i int) float64 {
+	n := e.Total.Value(i)
+	if n <= 0 {
+		return 0
+	}
+	return e.Pass.Value(i) / n
+}
+
+// Bounds returns the interval on the efficiency in bin i.
+func (e *Efficiency) Bounds(i int) (low, high float64) {
+	return BinomialInterval(e.Interval, e.Pass.Value(i), e.Total.Value(i), e.CL)
+}
+
+// ErrorPoints returns the efficiency at the bin centers, with asymmetric y
+// errors from the interval and x errors of the standard deviation of a
+// uniform distribution across the bin.  Empty bins are placed at zero with
+// no y errors.
+func (e *Efficiency) ErrorPoints() plotutil.ErrorPoints {
+	n := e.Len()
+	points := make(plotter.XYs, n)
+	xErrors := make(plotter.XErrors, n)
+	yErrors := make(plotter.YErrors, n)
+	for i := range points {
+		bin := e.Total.Binning.Bins[i]
+		binSigma := bin.XWidth() / 2 / math.Sqrt(3.)
+
+		points[i].X = bin.XMid()
+		xErrors[i].Low = binSigma
+		xErrors[i].High = binSigma
+
+		if bin.SumW() > 0 {
+			points[i].Y = e.Value(i)
+			low, high := e.Bounds(i)
+			yErrors[i].Low = math.Max(points[i].Y-low, 0)
+			yErrors[i].High = math.Max(high-points[i].Y, 0)
+		}
+	}
+	return plotutil.ErrorPoints{XYs: points, XErrors: xErrors, YErrors: yErrors}
+}
+
+// Plotters returns error bars and markers drawing the efficiency in the given
+// style, along with a matching legend thumbnail.
+func (e *Efficiency) Plotters(style SeriesStyle) ([]plot.Plotter, plot.Thumbnailer) {
+	errPoints := e.ErrorPoints()
+	xerr, _ := plotter.NewXErrorBars(errPoints)
+	yerr, _ := plotter.NewYErrorBars(errPoints)
+	points, _ := plotter.NewScatter(errPoints)
+
+	lineStyle := style.LineStyle()
+	// error bars are too short for dashes to be legible
+	lineStyle.Dashes = nil
+	xerr.LineStyle = lineStyle
+	yerr.LineStyle = lineStyle
+	points.GlyphStyle = style.GlyphStyle()
+
+	thumb := ErrorBarThumbnail{LineStyle: lineStyle, Glyph: points.GlyphStyle}
+	return []plot.Plotter{xerr, yerr, points}, thumb
+}
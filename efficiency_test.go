@@ -0,0 +1,70 @@
+package eicplot
+
+import (
+	"math"
+	"testing"
+)
+
+func TestBinomialInterval(t *testing.T) {
+	tests := []struct {
+		method    IntervalMethod
+		k, n      float64
+		low, high float64
+	}{
+		{ClopperPearson, 0, 10, 0, 1 - math.Pow(0.025, 0.1)},
+		{ClopperPearson, 10, 10, math.Pow(0.025, 0.1), 1},
+		{ClopperPearson, 5, 10, 0.18708602844739852, 0.8129139715526011},
+		{Wilson, 0, 10, 0, 0.27753279},
+		{Wilson, 10, 10, 0.72246721, 1},
+		{Wilson, 5, 10, 0.23659309, 0.76340691},
+		{Bayesian, 0, 10, 0, 1 - math.Pow(0.05, 1./11)},
+		{Bayesian, 10, 10, math.Pow(0.05, 1./11), 1},
+		{Bayesian, 5, 10, 0.23379359765934513, 0.7662064023406547},
+	}
+
+	for _, test := range tests {
+		low, high := BinomialInterval(test.method, test.k, test.n, 0.95)
+		if math.Abs(low-test.low) > 1e-6 || math.Abs(high-test.high) > 1e-6 {
+			t.Errorf("%v interval for %v of %v = [%v, %v], want [%v, %v]",
+				test.method.String(), test.k, test.n, low, high, test.low, test.high)
+		}
+	}
+}
+
+func TestBinomialIntervalEmpty(t *testing.T) {
+	for _, method := range []IntervalMethod{ClopperPearson, Wilson, Bayesian} {
+		if low, high := BinomialInterval(method, 0, 0, OneSigma); low != 0 || high != 1 {
+			t.Errorf("%v interval for no entries = [%v, %v], want [0, 1]", method.String(), low, high)
+		}
+	}
+}
+
+func TestEfficiencyValue(t *testing.T) {
+	eff := NewEfficiency(3, 0, 3)
+	eff.Total.Fill(0.5, 4)
+	eff.Pass.Fill(0.5, 1)
+	// clone tracks can pass more than once
+	eff.Total.Fill(1.5, 2)
+	eff.Pass.Fill(1.5, 3)
+
+	tests := []struct {
+		bin   int
+		value float64
+	}{
+		{0, 0.25},
+		{1, 1.5},
+		{2, 0},
+	}
+	for _, test := range tests {
+		if v := eff.Value(test.bin); v != test.value {
+			t.Errorf("bin %v: Value = %v, want %v", test.bin, v, test.value)
+		}
+	}
+
+	// the interval treats more passing than total entries as all passing
+	low, high := eff.Bounds(1)
+	wantLow, wantHigh := BinomialInterval(ClopperPearson, 2, 2, OneSigma)
+	if low != wantLow || high != wantHigh {
+		t.Errorf("Bounds with Pass > Total = [%v, %v], want [%v, %v]", low, high, wantLow, wantHigh)
+	}
+}
@@ -28,3 +28,16 @@ func (f *FloatArrayFlags) Set(valueStr string) error {
 func (f *FloatArrayFlags) String() string {
 	return fmt.Sprint(f.Array)
 }
+
+type StringArrayFlags struct {
+	Array []string
+}
+
+func (f *StringArrayFlags) Set(valueStr string) error {
+	f.Array = append(f.Array, valueStr)
+	return nil
+}
+
+func (f *StringArrayFlags) String() string {
+	return fmt.Sprint(f.Array)
+}
@@ -20,7 +20,7 @@ require (
 	golang.org/x/sync v0.0.0-20180314180146-1d60e4601c6f // indirect
 	golang.org/x/sys v0.0.0-20180926160741-c2ed4eda69e7 // indirect
 	golang.org/x/tools v0.0.0-20180926203008-ef4a2a23bb35 // indirect
-	gonum.org/v1/gonum v0.0.0-20180925042723-1b7b288aabab // indirect
+	gonum.org/v1/gonum v0.0.0-20180925042723-1b7b288aabab
 	gonum.org/v1/netlib v0.0.0-20180925085438-7a718cd5f57a // indirect
 	gonum.org/v1/plot v0.0.0-20180905080458-5f3c436ce602
 )
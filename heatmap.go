@@ -0,0 +1,100 @@
+package eicplot
+
+import (
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// HeatmapFigure draws a heatmap with a labelled color bar to its right.  The
+// color bar is sized to its tick labels and aligned with the data area of the
+// heatmap, so the figure can be drawn at any size.
+type HeatmapFigure struct {
+	Grid     plotter.GridXYZ
+	ColorMap palette.ColorMap
+	Min, Max float64
+
+	Title, XLabel, YLabel, ZLabel string
+
+	// XTicks and YTicks default to PreciseTicks, and ZTicks to the plot
+	// default.
+	XTicks, YTicks, ZTicks plot.Ticker
+
+	// ColorBarWidth is the width of the bar itself, excluding its axis.
+	ColorBarWidth vg.Length
+	// Gap separates the heatmap from the color bar axis.
+	Gap vg.Length
+}
+
+func NewHeatmapFigure(grid plotter.GridXYZ, colorMap palette.ColorMap, min, max float64) *HeatmapFigure {
+	colorMap.SetMin(min)
+	colorMap.SetMax(max)
+	return &HeatmapFigure{
+		Grid:          grid,
+		ColorMap:      colorMap,
+		Min:           min,
+		Max:           max,
+		XTicks:        PreciseTicks{NSuggestedTicks: 5},
+		YTicks:        PreciseTicks{NSuggestedTicks: 5},
+		ColorBarWidth: 0.25 * vg.Inch,
+		Gap:           0.15 * vg.Inch,
+	}
+}
+
+func (f *HeatmapFigure) Draw(c draw.Canvas) {
+	p, _ := plot.New()
+	p.Title.Text = f.Title
+	p.X.Label.Text = f.XLabel
+	p.Y.Label.Text = f.YLabel
+	if f.XTicks != nil {
+		p.X.Tick.Marker = f.XTicks
+	}
+	if f.YTicks != nil {
+		p.Y.Tick.Marker = f.YTicks
+	}
+
+	pal := f.ColorMap.Palette(1000)
+	heatMap := plotter.NewHeatMap(f.Grid, pal)
+	heatMap.Min = f.Min
+	heatMap.Max = f.Max
+	// cells outside the color range take the end colors, leaving only NaN
+	// cells, which have no value, transparent
+	colors := pal.Colors()
+	heatMap.Underflow = colors[0]
+	heatMap.Overflow = colors[len(colors)-1]
+	p.Add(heatMap)
+
+	bar, _ := plot.New()
+	bar.HideX()
+	bar.X.Padding = 0
+	bar.Y.Padding = 0
+	bar.Y.Label.Text = f.ZLabel
+	if f.ZTicks != nil {
+		bar.Y.Tick.Marker = f.ZTicks
+	}
+	bar.Add(&ColorBar{ColorMap: f.ColorMap})
+
+	barWidth := bar.DataCanvas(c).Min.X - c.Min.X + f.ColorBarWidth
+	// leave room above the data area for the top color bar tick label
+	mainCanvas := draw.Crop(c, 0, -barWidth-f.Gap, 0, -bar.Y.Tick.Label.Height("0")/2)
+	barCanvas := draw.Crop(c, c.Max.X-c.Min.X-barWidth, 0, 0, 0)
+
+	dataCanvas := p.DataCanvas(mainCanvas)
+	barCanvas.Min.Y = dataCanvas.Min.Y
+	barCanvas.Max.Y = dataCanvas.Max.Y
+
+	p.Draw(mainCanvas)
+	bar.Draw(barCanvas)
+}
+
+// Save writes the figure in the format implied by the extension of filename.
+func (f *HeatmapFigure) Save(w, h vg.Length, filename string) error {
+	c, err := NewCanvas(filename, w, h)
+	if err != nil {
+		return err
+	}
+	f.Draw(draw.New(c))
+	return SaveCanvas(c, filename)
+}
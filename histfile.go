@@ -0,0 +1,136 @@
+package eicplot
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hbook/yodacnv"
+	"go-hep.org/x/hep/rootio"
+)
+
+// HistFile collects filled histograms to be saved for replotting.  The
+// format is chosen from the extension of the file written, either .root or
+// .yoda.  Names must be unique, which Write checks.
+type HistFile struct {
+	h1ds  []*hbook.H1D
+	h2ds  []*hbook.H2D
+	names map[string]bool
+	err   error
+}
+
+func (f *HistFile) AddH1D(name, title string, h *hbook.H1D) {
+	f.addName(name)
+	h.Annotation()["name"] = name
+	h.Annotation()["title"] = title
+	f.h1ds = append(f.h1ds, h)
+}
+
+func (f *HistFile) AddH2D(name, title string, h *hbook.H2D) {
+	f.addName(name)
+	h.Annotation()["name"] = name
+	h.Annotation()["title"] = title
+	f.h2ds = append(f.h2ds, h)
+}
+
+// addName records the first duplicate name as an error.  Names built with
+// FileTag collide for input files of the same base name in different
+// directories.
+func (f *HistFile) addName(name string) {
+	if f.names == nil {
+		f.names = make(map[string]bool)
+	}
+	if f.names[name] && f.err == nil {
+		f.err = fmt.Errorf("duplicate histogram name %v (do input files share a base name?)", name)
+	}
+	f.names[name] = true
+}
+
+func (f *HistFile) Write(filename string) error {
+	if f.err != nil {
+		return f.err
+	}
+	switch filepath.Ext(filename) {
+	case ".root":
+		return f.writeROOT(filename)
+	case ".yoda":
+		return f.writeYODA(filename)
+	}
+	return fmt.Errorf("unknown histogram file format for %v", filename)
+}
+
+func (f *HistFile) writeROOT(filename string) error {
+	file, err := rootio.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	for _, h := range f.h1ds {
+		if err := file.Put(h.Name(), rootio.NewH1DFrom(h)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+	for _, h := range f.h2ds {
+		if err := file.Put(h.Name(), rootio.NewH2DFrom(h)); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	return file.Close()
+}
+
+func (f *HistFile) writeYODA(filename string) error {
+	file, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+
+	var objs []yodacnv.Marshaler
+	for _, h := range f.h1ds {
+		objs = append(objs, h)
+	}
+	for _, h := range f.h2ds {
+		objs = append(objs, h)
+	}
+
+	if err := yodacnv.Write(file, objs...); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// NewFlagHistFile registers the -hists command-line flag.
+func NewFlagHistFile() *string {
+	return flag.String("hists", "", "file to save filled histograms to (.root or .yoda)")
+}
+
+// HistName joins parts into a name that is valid as a ROOT key and a YODA
+// path.
+func HistName(parts ...string) string {
+	name := strings.Join(parts, "_")
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '.', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, name)
+}
+
+// FileTag shortens an input file name for use in histogram names.
+func FileTag(filename string) string {
+	base := filepath.Base(filename)
+	return strings.TrimSuffix(base, filepath.Ext(base))
+}
+
+// FormatCut formats a cut value for use in histogram names and labels.
+func FormatCut(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
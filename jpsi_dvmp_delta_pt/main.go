@@ -3,9 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/color"
 	"log"
-	"math"
 	"os"
 
 	"github.com/proio-org/go-proio"
@@ -13,13 +11,15 @@ import (
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
 
 options:
 `,
@@ -29,8 +29,16 @@ options:
 
 func main() {
 	var (
-		title  = flag.String("title", "", "plot title")
-		output = flag.String("output", "out.png", "output file")
+		title                = flag.String("title", "", "plot title")
+		output               = flag.String("output", "out.png", "output file")
+		nWorkers             = eicplot.NewFlagWorkers()
+		histsOut             = eicplot.NewFlagHistFile()
+		beams, beamMeta      = kin.NewFlagBeamSetup()
+		labels               = eicplot.NewFlagLabels()
+		legend               = eicplot.NewFlagLegend()
+		ratio                = eicplot.NewFlagRatio()
+		compare, drawCompare = eicplot.NewFlagCompare()
+		ref                  = eicplot.NewFlagReference()
 	)
 	flag.Usage = printUsage
 	flag.Parse()
@@ -38,6 +46,9 @@ func main() {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
+	if (*ratio || *compare) && (*ref < 0 || *ref >= flag.NArg()) {
+		log.Fatal("Reference is not an input file index")
+	}
 
 	p, _ := plot.New()
 	p.Title.Text = *title
@@ -46,45 +57,86 @@ func main() {
 	p.Y.Tick.Marker = eicplot.LogTicks{}
 	p.Y.Scale = eicplot.LogScale{}
 
+	histFile := &eicplot.HistFile{}
 	var hists []*hbook.H1D
+	var defaultLabels []string
 	for _, filename := range flag.Args() {
-		hists = append(hists, makeHists(filename)...)
+		fileHists := makeHists(filename, *beams, *beamMeta, *nWorkers)
+		tag := eicplot.FileTag(filename)
+		histFile.AddH1D(eicplot.HistName("deltapt_truth_proton", tag), "transverse momentum transfer from generated proton", fileHists[0])
+		histFile.AddH1D(eicplot.HistName("deltapt_reco_tracks", tag), "transverse momentum transfer from reconstructed tracks", fileHists[1])
+		hists = append(hists, fileHists...)
+
+		for _, label := range []string{"truth proton", "reco tracks"} {
+			if flag.NArg() > 1 {
+				label = tag + ": " + label
+			}
+			defaultLabels = append(defaultLabels, label)
+		}
 	}
 
+	var series []plotutil.ErrorPoints
 	for i, hist := range hists {
-		lineColor := color.RGBA{A: 255}
-		switch i {
-		case 1:
-			lineColor = color.RGBA{G: 255, A: 255}
-		case 2:
-			lineColor = color.RGBA{B: 255, A: 255}
-		case 3:
-			lineColor = color.RGBA{R: 255, B: 127, G: 127, A: 255}
-		}
+		series = append(series, eicplot.H1DErrorPoints(hist))
 
 		h := hplot.NewH1D(hist)
 		h.FillColor = nil
-		h.LineStyle.Color = lineColor
+		h.LineStyle = eicplot.Style(i).LineStyle()
 		h.Infos.Style = hplot.HInfoNone
 
 		p.Add(h)
+
+		label := eicplot.Label(labels, i, defaultLabels[i])
+		if iRef := *ref*2 + i%2; *compare && iRef != i {
+			c := eicplot.Compare(hist, hists[iRef])
+			fmt.Printf("%v vs %v: %v\n", label, eicplot.Label(labels, iRef, defaultLabels[iRef]), c)
+			if *drawCompare {
+				label += " (" + c.Summary() + ")"
+			}
+		}
+		legend.Add(p, label, h)
 	}
 
-	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	if *ratio {
+		fig := eicplot.NewRatioFigure(p)
+		fig.Ratio.Y.Label.Text = "ratio to " + eicplot.FileTag(flag.Arg(*ref))
+		fig.AddFileRatios(series, 2, *ref)
+		if err := fig.Save(6*vg.Inch, 5*vg.Inch, *output); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	}
+
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 const jpsiMass = 3.096916
 
-func makeHists(filename string) []*hbook.H1D {
-	deltaPTHist := hbook.NewH1D(50, 0, 4)
-	deltaPTTruthHist := hbook.NewH1D(50, 0, 4)
-
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+func makeHists(filename string, beams kin.BeamSetup, useMetadata bool, nWorkers int) []*hbook.H1D {
+	workerHists := make([][]*hbook.H1D, nWorkers)
+	workerBeams := make([]kin.BeamSetup, nWorkers)
+	for i := range workerHists {
+		workerHists[i] = []*hbook.H1D{
+			hbook.NewH1D(50, 0, 4),
+			hbook.NewH1D(50, 0, 4),
+		}
+		workerBeams[i] = beams
 	}
 
-	for event := range reader.ScanEvents() {
+	err := eicplot.ProcessEvents([]string{filename}, nWorkers, func(worker int, event *proio.Event) {
+		deltaPTTruthHist, deltaPTHist := workerHists[worker][0], workerHists[worker][1]
+		beams := &workerBeams[worker]
+
+		if useMetadata {
+			if err := beams.ReadMetadata(event.Metadata); err != nil {
+				log.Fatal(err)
+			}
+		}
 		ids := event.TaggedEntries("Reconstructed")
 		tracks := []*eic.Track{}
 		for _, id := range ids {
@@ -95,13 +147,11 @@ func makeHists(filename string) []*hbook.H1D {
 		}
 
 		if len(tracks) == 3 {
-			var p [2]float64
+			var final kin.Vec4
 			for _, track := range tracks {
-				p[0] += *track.Segment[0].Poq.X
-				p[1] += *track.Segment[0].Poq.Y
+				final = final.Add(kin.FromTrackSegment(track.Segment[0], 0))
 			}
-			deltaPT := math.Sqrt(math.Pow(p[0], 2) + math.Pow(p[1], 2))
-			deltaPTHist.Fill(deltaPT, 1)
+			deltaPTHist.Fill(beams.HeadOn(final).Pt(), 1)
 		}
 
 		ids = event.TaggedEntries("GenStable")
@@ -114,11 +164,12 @@ func makeHists(filename string) []*hbook.H1D {
 		}
 
 		if len(protons) == 1 {
-			deltaPT := math.Sqrt(math.Pow(float64(*protons[0].P.X), 2) + math.Pow(float64(*protons[0].P.Y), 2))
-			deltaPTTruthHist.Fill(deltaPT, 1)
+			deltaPTTruthHist.Fill(beams.HeadOn(kin.FromParticle(protons[0])).Pt(), 1)
 		}
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	reader.Close()
-	return []*hbook.H1D{deltaPTTruthHist, deltaPTHist}
+	return eicplot.MergeH1DSets(workerHists)
 }
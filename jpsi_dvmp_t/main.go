@@ -3,9 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/color"
 	"log"
-	"math"
 	"os"
 
 	"github.com/proio-org/go-proio"
@@ -13,13 +11,15 @@ import (
 	"go-hep.org/x/hep/hbook"
 	"go-hep.org/x/hep/hplot"
 	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
 
 options:
 `,
@@ -29,8 +29,16 @@ options:
 
 func main() {
 	var (
-		title  = flag.String("title", "", "plot title")
-		output = flag.String("output", "out.png", "output file")
+		title                = flag.String("title", "", "plot title")
+		output               = flag.String("output", "out.png", "output file")
+		nWorkers             = eicplot.NewFlagWorkers()
+		histsOut             = eicplot.NewFlagHistFile()
+		beams, beamMeta      = kin.NewFlagBeamSetup()
+		labels               = eicplot.NewFlagLabels()
+		legend               = eicplot.NewFlagLegend()
+		ratio                = eicplot.NewFlagRatio()
+		compare, drawCompare = eicplot.NewFlagCompare()
+		ref                  = eicplot.NewFlagReference()
 	)
 	flag.Usage = printUsage
 	flag.Parse()
@@ -38,6 +46,9 @@ func main() {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
+	if (*ratio || *compare) && (*ref < 0 || *ref >= flag.NArg()) {
+		log.Fatal("Reference is not an input file index")
+	}
 
 	p, _ := plot.New()
 	p.Title.Text = *title
@@ -46,46 +57,90 @@ func main() {
 	p.Y.Tick.Marker = eicplot.LogTicks{}
 	p.Y.Scale = eicplot.LogScale{}
 
+	histFile := &eicplot.HistFile{}
 	var hists []*hbook.H1D
+	var defaultLabels []string
 	for _, filename := range flag.Args() {
-		hists = append(hists, makeHists(filename)...)
+		fileHists := makeHists(filename, *beams, *beamMeta, *nWorkers)
+		tag := eicplot.FileTag(filename)
+		histFile.AddH1D(eicplot.HistName("t_truth_proton", tag), "-t from generated proton", fileHists[0])
+		histFile.AddH1D(eicplot.HistName("t_truth_leptons", tag), "-t from generated leptons", fileHists[1])
+		histFile.AddH1D(eicplot.HistName("t_reco_tracks", tag), "-t from reconstructed tracks", fileHists[2])
+		hists = append(hists, fileHists...)
+
+		for _, label := range []string{"truth proton", "truth leptons", "reco tracks"} {
+			if flag.NArg() > 1 {
+				label = tag + ": " + label
+			}
+			defaultLabels = append(defaultLabels, label)
+		}
 	}
 
+	var series []plotutil.ErrorPoints
 	for i, hist := range hists {
-		lineColor := color.RGBA{A: 255}
-		switch i {
-		case 1:
-			lineColor = color.RGBA{G: 255, A: 255}
-		case 2:
-			lineColor = color.RGBA{B: 255, A: 255}
-		case 3:
-			lineColor = color.RGBA{R: 255, B: 127, G: 127, A: 255}
-		}
+		series = append(series, eicplot.H1DErrorPoints(hist))
 
 		h := hplot.NewH1D(hist)
 		h.FillColor = nil
-		h.LineStyle.Color = lineColor
+		h.LineStyle = eicplot.Style(i).LineStyle()
 		h.Infos.Style = hplot.HInfoNone
 
 		p.Add(h)
+
+		label := eicplot.Label(labels, i, defaultLabels[i])
+		if iRef := *ref*3 + i%3; *compare && iRef != i {
+			c := eicplot.Compare(hist, hists[iRef])
+			fmt.Printf("%v vs %v: %v\n", label, eicplot.Label(labels, iRef, defaultLabels[iRef]), c)
+			if *drawCompare {
+				label += " (" + c.Summary() + ")"
+			}
+		}
+		legend.Add(p, label, h)
 	}
 
-	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	if *ratio {
+		fig := eicplot.NewRatioFigure(p)
+		fig.Ratio.Y.Label.Text = "ratio to " + eicplot.FileTag(flag.Arg(*ref))
+		fig.AddFileRatios(series, 3, *ref)
+		if err := fig.Save(6*vg.Inch, 5*vg.Inch, *output); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	}
+
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
 const jpsiMass = 3.096916
 
-func makeHists(filename string) []*hbook.H1D {
-	tHist := hbook.NewH1D(50, -1, 4)
-	tPTruthHist := hbook.NewH1D(50, -1, 4)
-	tETruthHist := hbook.NewH1D(50, -1, 4)
-
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+func makeHists(filename string, beams kin.BeamSetup, useMetadata bool, nWorkers int) []*hbook.H1D {
+	workerHists := make([][]*hbook.H1D, nWorkers)
+	workerBeams := make([]kin.BeamSetup, nWorkers)
+	for i := range workerHists {
+		workerHists[i] = []*hbook.H1D{
+			hbook.NewH1D(50, -1, 4),
+			hbook.NewH1D(50, -1, 4),
+			hbook.NewH1D(50, -1, 4),
+		}
+		workerBeams[i] = beams
 	}
 
-	for event := range reader.ScanEvents() {
+	err := eicplot.ProcessEvents([]string{filename}, nWorkers, func(worker int, event *proio.Event) {
+		tPTruthHist, tETruthHist, tHist := workerHists[worker][0], workerHists[worker][1], workerHists[worker][2]
+		beams := &workerBeams[worker]
+
+		if useMetadata {
+			if err := beams.ReadMetadata(event.Metadata); err != nil {
+				log.Fatal(err)
+			}
+		}
+		eBeam, pBeam := beams.LeptonBeam(), beams.HadronBeam()
+
 		ids := event.TaggedEntries("Reconstructed")
 		tracks := []*eic.Track{}
 		for _, id := range ids {
@@ -96,18 +151,11 @@ func makeHists(filename string) []*hbook.H1D {
 		}
 
 		if len(tracks) == 3 {
-			var p [4]float64
-			p[2] += 5. // 5 GeV e- beam
-			p[3] -= 5.
+			var final kin.Vec4
 			for _, track := range tracks {
-				p[0] += *track.Segment[0].Poq.X
-				p[1] += *track.Segment[0].Poq.Y
-				p[2] += *track.Segment[0].Poq.Z
-				p[3] += math.Sqrt(pSquareD(track.Segment[0].Poq))
+				final = final.Add(kin.FromTrackSegment(track.Segment[0], 0))
 			}
-			t := math.Pow(p[0], 2) + math.Pow(p[1], 2) + math.Pow(p[2], 2)
-			t -= math.Pow(p[3], 2)
-			tHist.Fill(t, 1)
+			tHist.Fill(-kin.T(eBeam, final), 1)
 		}
 
 		ids = event.TaggedEntries("GenStable")
@@ -125,41 +173,20 @@ func makeHists(filename string) []*hbook.H1D {
 		}
 
 		if len(protons) == 1 {
-			t := math.Pow(float64(*protons[0].P.X), 2) + math.Pow(float64(*protons[0].P.Y), 2) + math.Pow(float64(*protons[0].P.Z-100.), 2) //  100 GeV p+ beam
-			t -= math.Pow(math.Sqrt(pSquareF(protons[0].P)+math.Pow(float64(*protons[0].Mass), 2))-100., 2)
-			tPTruthHist.Fill(t, 1)
+			tPTruthHist.Fill(-kin.T(pBeam, kin.FromParticle(protons[0])), 1)
 		}
 
 		if len(leptons) == 3 {
-			var p [4]float64
-			p[2] += 5. // 5 GeV e- beam
-			p[3] -= 5.
+			var final kin.Vec4
 			for _, lepton := range leptons {
-				p[0] += float64(*lepton.P.X)
-				p[1] += float64(*lepton.P.Y)
-				p[2] += float64(*lepton.P.Z)
-				p[3] += math.Sqrt(pSquareF(lepton.P))
+				final = final.Add(kin.FromParticle(lepton))
 			}
-			t := math.Pow(p[0], 2) + math.Pow(p[1], 2) + math.Pow(p[2], 2)
-			t -= math.Pow(p[3], 2)
-			tETruthHist.Fill(t, 1)
+			tETruthHist.Fill(-kin.T(eBeam, final), 1)
 		}
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	reader.Close()
-	return []*hbook.H1D{tPTruthHist, tETruthHist, tHist}
-}
-
-func pSquareD(p *eic.XYZD) float64 {
-	px2 := math.Pow(float64(*p.X), 2)
-	py2 := math.Pow(float64(*p.Y), 2)
-	pz2 := math.Pow(float64(*p.Z), 2)
-	return px2 + py2 + pz2
-}
-
-func pSquareF(p *eic.XYZF) float64 {
-	px2 := math.Pow(float64(*p.X), 2)
-	py2 := math.Pow(float64(*p.Y), 2)
-	pz2 := math.Pow(float64(*p.Z), 2)
-	return px2 + py2 + pz2
+	return eicplot.MergeH1DSets(workerHists)
 }
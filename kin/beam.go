@@ -0,0 +1,181 @@
+package kin
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+type Species struct {
+	Name string
+	Mass float64
+	// Nucleons is the mass number of an ion, or 1 for other particles.
+	Nucleons int
+}
+
+var knownSpecies = []Species{
+	{"e-", ElectronMass, 1},
+	{"e+", ElectronMass, 1},
+	{"mu-", MuonMass, 1},
+	{"mu+", MuonMass, 1},
+	{"p", ProtonMass, 1},
+	{"d", 1.875612928, 2},
+	{"He3", 2.808391586, 3},
+	{"Au", 183.4732, 197},
+}
+
+func (s *Species) Set(valueStr string) error {
+	for _, species := range knownSpecies {
+		if species.Name == valueStr {
+			*s = species
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown beam species %q", valueStr)
+}
+
+func (s *Species) String() string {
+	return s.Name
+}
+
+// BeamSetup describes the colliding beams.  The lepton beam travels along -z,
+// and the hadron beam along +z rotated by CrossingAngle (in radians) about
+// the y axis.  HadronEnergy is per nucleon for ions.
+type BeamSetup struct {
+	Lepton, Hadron             Species
+	LeptonEnergy, HadronEnergy float64
+	CrossingAngle              float64
+
+	// fixed holds the metadata keys of beam flags given on the command
+	// line, which ReadMetadata leaves alone.
+	fixed map[string]bool
+}
+
+func (b *BeamSetup) LeptonBeam() Vec4 {
+	return NewBeam(b.Lepton.Mass, b.LeptonEnergy, Vec3{Z: -1})
+}
+
+func (b *BeamSetup) HadronBeam() Vec4 {
+	a := float64(b.Hadron.Nucleons)
+	dir := Vec3{X: math.Sin(b.CrossingAngle), Z: math.Cos(b.CrossingAngle)}
+	return NewBeam(b.Hadron.Mass, a*b.HadronEnergy, dir)
+}
+
+// HeadOn transforms v from the lab into the frame where the beams collide
+// head-on along z.  The transformation is a boost to the beam center-of-mass
+// frame, a rotation of the hadron beam onto +z, and a boost back along z with
+// the velocity of the nominal head-on beams.  Without a crossing angle it is
+// the identity.
+func (b *BeamSetup) HeadOn(v Vec4) Vec4 {
+	lepton, hadron := b.LeptonBeam(), b.HadronBeam()
+	toCM := lepton.Add(hadron).BoostVector().Scale(-1)
+
+	hadronCM := hadron.Boost(toCM)
+	v = v.Boost(toCM).RotateY(-math.Atan2(hadronCM.P.X, hadronCM.P.Z))
+
+	headOn := *b
+	headOn.CrossingAngle = 0
+	fromCM := headOn.LeptonBeam().Add(headOn.HadronBeam()).BoostVector()
+	return v.Boost(fromCM)
+}
+
+var beamMetadataKeys = []string{
+	"beam.lepton",
+	"beam.leptonenergy",
+	"beam.hadron",
+	"beam.hadronenergy",
+	"beam.crossingangle",
+}
+
+// ReadMetadata overrides the setup with any beam.* entries in proio event
+// metadata, except for those set explicitly by command-line flags.
+func (b *BeamSetup) ReadMetadata(metadata map[string][]byte) error {
+	for _, key := range beamMetadataKeys {
+		value, ok := metadata[key]
+		if !ok || b.fixed[key] {
+			continue
+		}
+
+		var err error
+		switch key {
+		case "beam.lepton":
+			err = b.Lepton.Set(string(value))
+		case "beam.hadron":
+			err = b.Hadron.Set(string(value))
+		case "beam.leptonenergy":
+			b.LeptonEnergy, err = strconv.ParseFloat(string(value), 64)
+		case "beam.hadronenergy":
+			b.HadronEnergy, err = strconv.ParseFloat(string(value), 64)
+		case "beam.crossingangle":
+			b.CrossingAngle, err = strconv.ParseFloat(string(value), 64)
+		}
+		if err != nil {
+			return fmt.Errorf("metadata %v: %v", key, err)
+		}
+	}
+	return nil
+}
+
+// NewFlagBeamSetup returns a BeamSetup configured by beam command-line flags,
+// which it registers with the flag package.  The returned bool pointer
+// reports whether file metadata should override the flags that are not given
+// explicitly.
+func NewFlagBeamSetup() (*BeamSetup, *bool) {
+	b := &BeamSetup{LeptonEnergy: 5, HadronEnergy: 100, fixed: make(map[string]bool)}
+	b.Lepton.Set("e-")
+	b.Hadron.Set("p")
+	b.flag(&b.Lepton, "lepton", "lepton beam `species`")
+	b.flag(&b.Hadron, "hadron", "hadron beam `species`")
+	b.flag(floatValue{&b.LeptonEnergy}, "leptonenergy", "lepton beam `energy` (GeV)")
+	b.flag(floatValue{&b.HadronEnergy}, "hadronenergy", "hadron beam `energy` per nucleon (GeV)")
+	b.flag(floatValue{&b.CrossingAngle}, "crossingangle", "beam crossing `angle` (rad)")
+	useMetadata := flag.Bool("beammeta", true, "take beam setup not given by flags from file metadata when present")
+	return b, useMetadata
+}
+
+func (b *BeamSetup) flag(value flag.Value, name, usage string) {
+	flag.Var(fixingValue{value, "beam." + name, b.fixed}, name, usage)
+}
+
+// fixingValue marks its metadata key as fixed when the flag is set.
+type fixingValue struct {
+	flag.Value
+	key   string
+	fixed map[string]bool
+}
+
+func (v fixingValue) Set(valueStr string) error {
+	if err := v.Value.Set(valueStr); err != nil {
+		return err
+	}
+	v.fixed[v.key] = true
+	return nil
+}
+
+func (v fixingValue) String() string {
+	if v.Value == nil {
+		return ""
+	}
+	return v.Value.String()
+}
+
+type floatValue struct {
+	p *float64
+}
+
+func (v floatValue) Set(valueStr string) error {
+	x, err := strconv.ParseFloat(valueStr, 64)
+	if err != nil {
+		return err
+	}
+	*v.p = x
+	return nil
+}
+
+func (v floatValue) String() string {
+	if v.p == nil {
+		return "0"
+	}
+	return strconv.FormatFloat(*v.p, 'g', -1, 64)
+}
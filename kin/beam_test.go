@@ -0,0 +1,76 @@
+package kin
+
+import (
+	"math"
+	"testing"
+)
+
+func TestHeadOn(t *testing.T) {
+	b := &BeamSetup{LeptonEnergy: 18, HadronEnergy: 275}
+	b.Lepton.Set("e-")
+	b.Hadron.Set("p")
+
+	v := NewVec4(Vec3{0.5, -0.2, 30}, ProtonMass)
+	if got := b.HeadOn(v); !approx(got.P.X, v.P.X) || !approx(got.P.Z, v.P.Z) || !approx(got.E, v.E) {
+		t.Errorf("HeadOn without crossing angle = %v, want %v", got, v)
+	}
+
+	b.CrossingAngle = 0.025
+	lepton, hadron := b.HeadOn(b.LeptonBeam()), b.HeadOn(b.HadronBeam())
+	if !approx(lepton.Pt(), 0) || lepton.P.Z > 0 {
+		t.Errorf("head-on lepton beam = %v, want along -z", lepton)
+	}
+	if !approx(hadron.Pt(), 0) || hadron.P.Z < 0 {
+		t.Errorf("head-on hadron beam = %v, want along +z", hadron)
+	}
+	if math.Abs(hadron.E-275) > 0.1 {
+		t.Errorf("head-on hadron beam energy = %v, want about 275", hadron.E)
+	}
+
+	// t is invariant under the transformation
+	out := NewVec4(Vec3{0.3, 0.1, 274}, ProtonMass)
+	if !approx(T(b.HadronBeam(), out), T(hadron, b.HeadOn(out))) {
+		t.Errorf("t changed by head-on transformation")
+	}
+}
+
+func TestReadMetadata(t *testing.T) {
+	b := &BeamSetup{LeptonEnergy: 5, HadronEnergy: 100}
+	b.Lepton.Set("e-")
+	b.Hadron.Set("p")
+
+	err := b.ReadMetadata(map[string][]byte{
+		"beam.leptonenergy":  []byte("10"),
+		"beam.hadron":        []byte("Au"),
+		"beam.crossingangle": []byte("0.025"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.LeptonEnergy != 10 || b.HadronEnergy != 100 || b.Hadron.Name != "Au" || b.CrossingAngle != 0.025 {
+		t.Errorf("setup after metadata = %+v", b)
+	}
+	if e := b.HadronBeam().E; !approx(e, 19700) {
+		t.Errorf("Au beam energy = %v, want 19700", e)
+	}
+	if math.Abs(b.HadronBeam().P.Theta()-0.025) > tol {
+		t.Errorf("hadron beam angle = %v, want 0.025", b.HadronBeam().P.Theta())
+	}
+
+	if err := b.ReadMetadata(map[string][]byte{"beam.lepton": []byte("x")}); err == nil {
+		t.Error("expected error for unknown species")
+	}
+
+	b.fixed = map[string]bool{"beam.leptonenergy": true}
+	err = b.ReadMetadata(map[string][]byte{
+		"beam.leptonenergy":  []byte("18"),
+		"beam.hadronenergy":  []byte("275"),
+		"beam.crossingangle": []byte("0"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b.LeptonEnergy != 10 || b.HadronEnergy != 275 || b.CrossingAngle != 0 {
+		t.Errorf("setup after metadata with fixed lepton energy = %+v", b)
+	}
+}
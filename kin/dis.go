@@ -0,0 +1,56 @@
+package kin
+
+import "math"
+
+// DIS holds the inclusive kinematics of a deep inelastic scattering event,
+// with x and W per nucleon of the hadron beam.
+type DIS struct {
+	X, Q2, Y, W float64
+}
+
+// newDIS completes the kinematics from Q^2 and y, which is invalid if y is
+// not in (0, 1].
+func newDIS(lepton, hadron Vec4, q2, y float64) (DIS, bool) {
+	if !(y > 0 && y <= 1) || !(q2 > 0) {
+		return DIS{}, false
+	}
+	pk2 := 2 * hadron.Dot(lepton)
+	d := DIS{Q2: q2, Y: y, X: q2 / (y * pk2)}
+	d.W = math.Sqrt(math.Max(hadron.M2()+y*pk2-q2, 0))
+	return d, true
+}
+
+// DISFromElectron computes the kinematics from the beams and the scattered
+// lepton, which may be given in any frame.  hadron is the four-momentum per
+// nucleon.
+func DISFromElectron(lepton, hadron, scattered Vec4) (DIS, bool) {
+	q := lepton.Sub(scattered)
+	return newDIS(lepton, hadron, -q.M2(), hadron.Dot(q)/hadron.Dot(lepton))
+}
+
+// DISJacquetBlondel computes the kinematics from the sum of the hadronic
+// final state.  All vectors are in the head-on frame with the lepton beam
+// along -z.
+func DISJacquetBlondel(lepton, hadron, hadrons Vec4) (DIS, bool) {
+	y := (hadrons.E - hadrons.P.Z) / (2 * lepton.E)
+	return newDIS(lepton, hadron, hadrons.Pt()*hadrons.Pt()/(1-y), y)
+}
+
+// DISDoubleAngle computes the kinematics from the polar angles of the
+// scattered lepton and of the hadronic final state, which are independent of
+// the energy scales to first order.  All vectors are in the head-on frame
+// with the lepton beam along -z.
+func DISDoubleAngle(lepton, hadron, scattered, hadrons Vec4) (DIS, bool) {
+	theta := scattered.Theta()
+	pt2 := hadrons.Pt() * hadrons.Pt()
+	delta := hadrons.E - hadrons.P.Z
+	gamma := math.Acos((pt2 - delta*delta) / (pt2 + delta*delta))
+
+	denom := math.Sin(gamma) + math.Sin(theta) - math.Sin(theta+gamma)
+	if !(denom > 0) {
+		return DIS{}, false
+	}
+	q2 := 4 * lepton.E * lepton.E * math.Sin(gamma) * (1 + math.Cos(theta)) / denom
+	y := math.Sin(theta) * (1 - math.Cos(gamma)) / denom
+	return newDIS(lepton, hadron, q2, y)
+}
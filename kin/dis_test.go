@@ -0,0 +1,61 @@
+package kin
+
+import (
+	"math"
+	"testing"
+)
+
+func TestDIS(t *testing.T) {
+	const eE, eP = 10., 100.
+	lepton := NewBeam(ElectronMass, eE, Vec3{Z: -1})
+	hadron := NewBeam(ProtonMass, eP, Vec3{Z: 1})
+
+	for _, want := range []DIS{
+		{X: 0.01, Q2: 10},
+		{X: 0.1, Q2: 100},
+		{X: 0.001, Q2: 2},
+	} {
+		// massless kinematics of the scattered lepton
+		want.Y = want.Q2 / (want.X * 4 * eE * eP)
+		ePrime := eE*(1-want.Y) + want.Q2/(4*eE)
+		theta := math.Acos(want.Q2/(2*eE*ePrime) - 1)
+		scattered := NewVec4(Vec3{X: math.Sin(theta), Z: math.Cos(theta)}.Scale(ePrime), 0)
+		hadrons := lepton.Add(hadron).Sub(scattered)
+
+		methods := []struct {
+			name string
+			f    func() (DIS, bool)
+		}{
+			{"electron", func() (DIS, bool) { return DISFromElectron(lepton, hadron, scattered) }},
+			{"Jacquet-Blondel", func() (DIS, bool) { return DISJacquetBlondel(lepton, hadron, hadrons) }},
+			{"double angle", func() (DIS, bool) { return DISDoubleAngle(lepton, hadron, scattered, hadrons) }},
+		}
+		for _, method := range methods {
+			got, ok := method.f()
+			if !ok {
+				t.Errorf("%v %+v: invalid", method.name, want)
+				continue
+			}
+			// the construction neglects the beam masses
+			const relTol = 1e-3
+			if math.Abs(got.X/want.X-1) > relTol || math.Abs(got.Q2/want.Q2-1) > relTol || math.Abs(got.Y/want.Y-1) > relTol {
+				t.Errorf("%v: got %+v, want %+v", method.name, got, want)
+			}
+			if w := hadrons.M(); math.Abs(got.W/w-1) > relTol {
+				t.Errorf("%v: W = %v, want %v", method.name, got.W, w)
+			}
+		}
+	}
+}
+
+func TestDISInvalid(t *testing.T) {
+	lepton := NewBeam(ElectronMass, 10, Vec3{Z: -1})
+	hadron := NewBeam(ProtonMass, 100, Vec3{Z: 1})
+
+	if _, ok := DISFromElectron(lepton, hadron, lepton); ok {
+		t.Error("unscattered lepton: valid")
+	}
+	if _, ok := DISJacquetBlondel(lepton, hadron, Vec4{}); ok {
+		t.Error("empty hadronic final state: valid")
+	}
+}
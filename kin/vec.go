@@ -0,0 +1,200 @@
+// Package kin provides three- and four-vectors for the kinematics of proio
+// EIC model types.
+package kin
+
+import (
+	"math"
+
+	"github.com/proio-org/go-proio-pb/model/eic"
+)
+
+// Particle masses in GeV
+const (
+	ElectronMass = 0.000510998928
+	MuonMass     = 0.1056583715
+	PionMass     = 0.13957018
+	KaonMass     = 0.493677
+	ProtonMass   = 0.938272046
+)
+
+type Vec3 struct {
+	X, Y, Z float64
+}
+
+func Vec3FromXYZD(v *eic.XYZD) Vec3 {
+	return Vec3{v.GetX(), v.GetY(), v.GetZ()}
+}
+
+func Vec3FromXYZF(v *eic.XYZF) Vec3 {
+	return Vec3{float64(v.GetX()), float64(v.GetY()), float64(v.GetZ())}
+}
+
+// Vec3FromXYZTD drops the time component.
+func Vec3FromXYZTD(v *eic.XYZTD) Vec3 {
+	return Vec3{v.GetX(), v.GetY(), v.GetZ()}
+}
+
+func (v Vec3) Add(u Vec3) Vec3 {
+	return Vec3{v.X + u.X, v.Y + u.Y, v.Z + u.Z}
+}
+
+func (v Vec3) Sub(u Vec3) Vec3 {
+	return Vec3{v.X - u.X, v.Y - u.Y, v.Z - u.Z}
+}
+
+func (v Vec3) Scale(a float64) Vec3 {
+	return Vec3{a * v.X, a * v.Y, a * v.Z}
+}
+
+func (v Vec3) Dot(u Vec3) float64 {
+	return v.X*u.X + v.Y*u.Y + v.Z*u.Z
+}
+
+func (v Vec3) Mag2() float64 {
+	return v.Dot(v)
+}
+
+func (v Vec3) Mag() float64 {
+	return math.Sqrt(v.Mag2())
+}
+
+func (v Vec3) Unit() Vec3 {
+	mag := v.Mag()
+	if mag == 0 {
+		return v
+	}
+	return v.Scale(1 / mag)
+}
+
+// RotateY rotates v by angle (in radians) about the y axis.
+func (v Vec3) RotateY(angle float64) Vec3 {
+	sin, cos := math.Sincos(angle)
+	return Vec3{cos*v.X + sin*v.Z, v.Y, cos*v.Z - sin*v.X}
+}
+
+// Pt returns the magnitude of the component transverse to the z axis.
+func (v Vec3) Pt() float64 {
+	return math.Hypot(v.X, v.Y)
+}
+
+func (v Vec3) Phi() float64 {
+	return math.Atan2(v.Y, v.X)
+}
+
+func (v Vec3) Theta() float64 {
+	return math.Atan2(v.Pt(), v.Z)
+}
+
+// Eta returns the pseudorapidity, which is infinite along the z axis.
+func (v Vec3) Eta() float64 {
+	return math.Atanh(v.Z / v.Mag())
+}
+
+// Vec4 is a Lorentz vector with metric (+, -, -, -).
+type Vec4 struct {
+	P Vec3
+	E float64
+}
+
+func NewVec4(p Vec3, mass float64) Vec4 {
+	return Vec4{P: p, E: math.Sqrt(p.Mag2() + mass*mass)}
+}
+
+// FromParticle uses the particle momentum and mass, preferring the double
+// precision fields if they are set.
+func FromParticle(part *eic.Particle) Vec4 {
+	if part.PDouble != nil {
+		return NewVec4(Vec3FromXYZD(part.PDouble), part.GetMassDouble())
+	}
+	return NewVec4(Vec3FromXYZF(part.P), float64(part.GetMass()))
+}
+
+// FromTrackSegment uses the segment momentum over charge, assuming unit
+// charge, together with the given mass hypothesis.
+func FromTrackSegment(seg *eic.TrackSegment, mass float64) Vec4 {
+	return NewVec4(Vec3FromXYZD(seg.Poq), mass)
+}
+
+// NewBeam returns the four-momentum of a beam particle of the given mass
+// and total energy moving in direction dir.
+func NewBeam(mass, energy float64, dir Vec3) Vec4 {
+	pMag := math.Sqrt(math.Max(energy*energy-mass*mass, 0))
+	return Vec4{P: dir.Unit().Scale(pMag), E: energy}
+}
+
+func (v Vec4) Add(u Vec4) Vec4 {
+	return Vec4{P: v.P.Add(u.P), E: v.E + u.E}
+}
+
+func (v Vec4) Sub(u Vec4) Vec4 {
+	return Vec4{P: v.P.Sub(u.P), E: v.E - u.E}
+}
+
+func (v Vec4) Dot(u Vec4) float64 {
+	return v.E*u.E - v.P.Dot(u.P)
+}
+
+func (v Vec4) M2() float64 {
+	return v.Dot(v)
+}
+
+// M returns the invariant mass, which is negative for space-like vectors.
+func (v Vec4) M() float64 {
+	m2 := v.M2()
+	if m2 < 0 {
+		return -math.Sqrt(-m2)
+	}
+	return math.Sqrt(m2)
+}
+
+func (v Vec4) Pt() float64 {
+	return v.P.Pt()
+}
+
+func (v Vec4) Eta() float64 {
+	return v.P.Eta()
+}
+
+func (v Vec4) Phi() float64 {
+	return v.P.Phi()
+}
+
+func (v Vec4) Theta() float64 {
+	return v.P.Theta()
+}
+
+func (v Vec4) Rapidity() float64 {
+	return 0.5 * math.Log((v.E+v.P.Z)/(v.E-v.P.Z))
+}
+
+func (v Vec4) RotateY(angle float64) Vec4 {
+	return Vec4{P: v.P.RotateY(angle), E: v.E}
+}
+
+// BoostVector returns the velocity of the frame in which v is at rest.
+func (v Vec4) BoostVector() Vec3 {
+	return v.P.Scale(1 / v.E)
+}
+
+// Boost transforms v into a frame moving with velocity -b, so that boosting a
+// vector by minus its own BoostVector brings it to rest.
+func (v Vec4) Boost(b Vec3) Vec4 {
+	b2 := b.Mag2()
+	if b2 == 0 {
+		return v
+	}
+	gamma := 1 / math.Sqrt(1-b2)
+	bp := b.Dot(v.P)
+	gamma2 := (gamma - 1) / b2
+
+	return Vec4{
+		P: v.P.Add(b.Scale(gamma2*bp + gamma*v.E)),
+		E: gamma * (v.E + bp),
+	}
+}
+
+// T returns the Mandelstam t, the squared four-momentum transfer between
+// incoming a and outgoing b.
+func T(a, b Vec4) float64 {
+	return b.Sub(a).M2()
+}
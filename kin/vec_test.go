@@ -0,0 +1,129 @@
+package kin
+
+import (
+	"math"
+	"testing"
+
+	"github.com/proio-org/go-proio-pb/model/eic"
+)
+
+const tol = 1e-9
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) <= tol*math.Max(1, math.Max(math.Abs(a), math.Abs(b)))
+}
+
+func TestVec3Angles(t *testing.T) {
+	tests := []struct {
+		v                   Vec3
+		pt, eta, phi, theta float64
+	}{
+		{Vec3{1, 0, 0}, 1, 0, 0, math.Pi / 2},
+		{Vec3{0, 2, 0}, 2, 0, math.Pi / 2, math.Pi / 2},
+		{Vec3{1, 0, 1}, 1, math.Asinh(1), 0, math.Pi / 4},
+		{Vec3{-1, 0, -1}, 1, -math.Asinh(1), math.Pi, 3 * math.Pi / 4},
+		{Vec3{3, 4, 12}, 5, math.Asinh(12. / 5), math.Atan2(4, 3), math.Atan2(5, 12)},
+	}
+
+	for _, test := range tests {
+		if !approx(test.v.Pt(), test.pt) {
+			t.Errorf("%v: Pt = %v, want %v", test.v, test.v.Pt(), test.pt)
+		}
+		if !approx(test.v.Eta(), test.eta) {
+			t.Errorf("%v: Eta = %v, want %v", test.v, test.v.Eta(), test.eta)
+		}
+		if !approx(test.v.Phi(), test.phi) {
+			t.Errorf("%v: Phi = %v, want %v", test.v, test.v.Phi(), test.phi)
+		}
+		if !approx(test.v.Theta(), test.theta) {
+			t.Errorf("%v: Theta = %v, want %v", test.v, test.v.Theta(), test.theta)
+		}
+	}
+}
+
+func TestInvariantMass(t *testing.T) {
+	const m = 3.096916
+	// back-to-back massless daughters of a particle at rest
+	d1 := NewVec4(Vec3{0, 0, m / 2}, 0)
+	d2 := NewVec4(Vec3{0, 0, -m / 2}, 0)
+	if got := d1.Add(d2).M(); !approx(got, m) {
+		t.Errorf("M = %v, want %v", got, m)
+	}
+
+	v := NewVec4(Vec3{1, 2, 3}, ProtonMass)
+	if !approx(v.M(), ProtonMass) {
+		t.Errorf("M = %v, want %v", v.M(), ProtonMass)
+	}
+
+	spaceLike := Vec4{P: Vec3{0, 0, 2}, E: 1}
+	if !approx(spaceLike.M(), -math.Sqrt(3)) {
+		t.Errorf("M = %v, want %v", spaceLike.M(), -math.Sqrt(3))
+	}
+}
+
+func TestRapidity(t *testing.T) {
+	v := NewVec4(Vec3{0.3, -0.4, 2}, KaonMass)
+	mt := math.Sqrt(KaonMass*KaonMass + v.Pt()*v.Pt())
+	if want := math.Asinh(v.P.Z / mt); !approx(v.Rapidity(), want) {
+		t.Errorf("Rapidity = %v, want %v", v.Rapidity(), want)
+	}
+
+	massless := NewVec4(Vec3{0.3, -0.4, 2}, 0)
+	if !approx(massless.Rapidity(), massless.Eta()) {
+		t.Errorf("massless Rapidity = %v, want Eta = %v", massless.Rapidity(), massless.Eta())
+	}
+}
+
+func TestBoost(t *testing.T) {
+	v := NewVec4(Vec3{1, -2, 5}, PionMass)
+
+	rest := v.Boost(v.BoostVector().Scale(-1))
+	if !approx(rest.P.Mag(), 0) || !approx(rest.E, PionMass) {
+		t.Errorf("rest frame vector = %v, want (0, 0, 0; %v)", rest, PionMass)
+	}
+
+	b := Vec3{0.1, 0.2, -0.6}
+	boosted := v.Boost(b)
+	if !approx(boosted.M2(), v.M2()) {
+		t.Errorf("boosted M2 = %v, want %v", boosted.M2(), v.M2())
+	}
+	back := boosted.Boost(b.Scale(-1))
+	if !approx(back.P.X, v.P.X) || !approx(back.P.Y, v.P.Y) || !approx(back.P.Z, v.P.Z) || !approx(back.E, v.E) {
+		t.Errorf("boost round trip = %v, want %v", back, v)
+	}
+}
+
+func TestT(t *testing.T) {
+	beam := NewBeam(ProtonMass, 100, Vec3{0, 0, 1})
+	if !approx(beam.M(), ProtonMass) {
+		t.Errorf("beam M = %v, want %v", beam.M(), ProtonMass)
+	}
+
+	if got := T(beam, beam); !approx(got, 0) {
+		t.Errorf("T for no scattering = %v, want 0", got)
+	}
+
+	// elastic scattering of a massless particle through angle theta
+	const e, theta = 10., 0.1
+	in := NewBeam(0, e, Vec3{0, 0, 1})
+	out := NewVec4(Vec3{e * math.Sin(theta), 0, e * math.Cos(theta)}, 0)
+	if want := -2 * e * e * (1 - math.Cos(theta)); !approx(T(in, out), want) {
+		t.Errorf("T = %v, want %v", T(in, out), want)
+	}
+}
+
+func TestConstructors(t *testing.T) {
+	px, py, pz, mass := float32(1), float32(2), float32(2), float32(ElectronMass)
+	part := &eic.Particle{P: &eic.XYZF{X: &px, Y: &py, Z: &pz}, Mass: &mass}
+	v := FromParticle(part)
+	if !approx(v.P.Mag(), 3) || !approx(v.M(), float64(mass)) {
+		t.Errorf("FromParticle = %v, want |p| = 3 and M = %v", v, mass)
+	}
+
+	x, y, z := 0.6, 0., 0.8
+	seg := &eic.TrackSegment{Poq: &eic.XYZD{X: &x, Y: &y, Z: &z}}
+	v = FromTrackSegment(seg, PionMass)
+	if !approx(v.P.Mag(), 1) || !approx(v.E, math.Sqrt(1+PionMass*PionMass)) {
+		t.Errorf("FromTrackSegment = %v, want |p| = 1 and M = %v", v, PionMass)
+	}
+}
@@ -0,0 +1,93 @@
+package eicplot
+
+import (
+	"flag"
+	"fmt"
+
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+type LegendPosition int
+
+const (
+	TopRight LegendPosition = iota
+	TopLeft
+	BottomRight
+	BottomLeft
+	NoLegend
+)
+
+var legendPositionNames = map[LegendPosition]string{
+	TopRight:    "top-right",
+	TopLeft:     "top-left",
+	BottomRight: "bottom-right",
+	BottomLeft:  "bottom-left",
+	NoLegend:    "none",
+}
+
+func (l *LegendPosition) Set(valueStr string) error {
+	for pos, name := range legendPositionNames {
+		if name == valueStr {
+			*l = pos
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown legend position %q", valueStr)
+}
+
+func (l *LegendPosition) String() string {
+	return legendPositionNames[*l]
+}
+
+// Add places the legend of p and adds an entry to it, unless the legend is
+// disabled.
+func (l LegendPosition) Add(p *plot.Plot, label string, thumbs ...plot.Thumbnailer) {
+	if l == NoLegend {
+		return
+	}
+	p.Legend.Top = l == TopRight || l == TopLeft
+	p.Legend.Left = l == TopLeft || l == BottomLeft
+	p.Legend.Add(label, thumbs...)
+}
+
+// NewFlagLegend registers the -legend command-line flag.
+func NewFlagLegend() *LegendPosition {
+	pos := new(LegendPosition)
+	flag.Var(pos, "legend", "legend position (top-right, top-left, bottom-right, bottom-left or none)")
+	return pos
+}
+
+// NewFlagLabels registers the repeatable -label command-line flag.
+func NewFlagLabels() *StringArrayFlags {
+	labels := &StringArrayFlags{}
+	flag.Var(labels, "label", "legend label of the next series, in the order plotted (may be repeated)")
+	return labels
+}
+
+// Label returns the i'th of labels given on the command line, or def if too
+// few were given.
+func Label(labels *StringArrayFlags, i int, def string) string {
+	if i < len(labels.Array) {
+		return labels.Array[i]
+	}
+	return def
+}
+
+// ErrorBarThumbnail draws a cross for legend entries of error bar series.
+type ErrorBarThumbnail struct {
+	draw.LineStyle
+	// Glyph is drawn at the center if its Shape is set.
+	Glyph draw.GlyphStyle
+}
+
+func (t ErrorBarThumbnail) Thumbnail(c *draw.Canvas) {
+	center := c.Center()
+	halfWidth := (c.Max.X - c.Min.X) / 4
+	halfHeight := (c.Max.Y - c.Min.Y) / 2
+	c.StrokeLine2(t.LineStyle, center.X-halfWidth, center.Y, center.X+halfWidth, center.Y)
+	c.StrokeLine2(t.LineStyle, center.X, center.Y-halfHeight, center.X, center.Y+halfHeight)
+	if t.Glyph.Shape != nil {
+		c.DrawGlyph(t.Glyph, center)
+	}
+}
@@ -0,0 +1,58 @@
+package eicplot
+
+import (
+	"go-hep.org/x/hep/hbook"
+)
+
+// MergeH1D adds the contents of src to dst, which must have identical binning.
+func MergeH1D(dst, src *hbook.H1D) {
+	dstBng, srcBng := &dst.Binning, &src.Binning
+	for i := range dstBng.Bins {
+		addDist1D(&dstBng.Bins[i].Dist, &srcBng.Bins[i].Dist)
+	}
+	for i := range dstBng.Outflows {
+		addDist1D(&dstBng.Outflows[i], &srcBng.Outflows[i])
+	}
+	addDist1D(&dstBng.Dist, &srcBng.Dist)
+}
+
+// MergeH2D adds the contents of src to dst, which must have identical binning.
+func MergeH2D(dst, src *hbook.H2D) {
+	dstBng, srcBng := &dst.Binning, &src.Binning
+	for i := range dstBng.Bins {
+		addDist2D(&dstBng.Bins[i].Dist, &srcBng.Bins[i].Dist)
+	}
+	for i := range dstBng.Outflows {
+		addDist2D(&dstBng.Outflows[i], &srcBng.Outflows[i])
+	}
+	addDist2D(&dstBng.Dist, &srcBng.Dist)
+}
+
+func addDist0D(dst, src *hbook.Dist0D) {
+	dst.N += src.N
+	dst.SumW += src.SumW
+	dst.SumW2 += src.SumW2
+}
+
+func addDist1D(dst, src *hbook.Dist1D) {
+	addDist0D(&dst.Dist, &src.Dist)
+	dst.SumWX += src.SumWX
+	dst.SumWX2 += src.SumWX2
+}
+
+func addDist2D(dst, src *hbook.Dist2D) {
+	addDist1D(&dst.X, &src.X)
+	addDist1D(&dst.Y, &src.Y)
+	dst.SumWXY += src.SumWXY
+}
+
+// MergeH1DSets merges per-worker sets of histograms, each with the same
+// layout, into the first set and returns it.
+func MergeH1DSets(sets [][]*hbook.H1D) []*hbook.H1D {
+	for _, set := range sets[1:] {
+		for i, h := range set {
+			MergeH1D(sets[0][i], h)
+		}
+	}
+	return sets[0]
+}
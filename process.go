@@ -0,0 +1,148 @@
+package eicplot
+
+import (
+	"flag"
+	"runtime"
+	"sync"
+
+	"github.com/proio-org/go-proio"
+)
+
+// ProcessEvents reads the events of all files and calls process for each of
+// them on one of nWorkers goroutines.  Events are handed to whichever worker
+// is free, so process should fill accumulators owned by the given worker
+// index and these should be merged after ProcessEvents returns.  Bin contents
+// then agree with a serial run; quantities computed from the order of fills
+// should use ProcessOrderedEvents.
+func ProcessEvents(filenames []string, nWorkers int, process func(worker int, event *proio.Event)) error {
+	if nWorkers < 1 {
+		nWorkers = 1
+	}
+
+	readers, err := openReaders(filenames)
+	if err != nil {
+		return err
+	}
+
+	events := make(chan *proio.Event, 2*nWorkers)
+
+	var readWG sync.WaitGroup
+	for _, reader := range readers {
+		readWG.Add(1)
+		go func(reader *proio.Reader) {
+			defer readWG.Done()
+			for event := range reader.ScanEvents() {
+				events <- event
+			}
+			reader.Close()
+		}(reader)
+	}
+	go func() {
+		readWG.Wait()
+		close(events)
+	}()
+
+	var workWG sync.WaitGroup
+	for worker := 0; worker < nWorkers; worker++ {
+		workWG.Add(1)
+		go func(worker int) {
+			defer workWG.Done()
+			for event := range events {
+				process(worker, event)
+			}
+		}(worker)
+	}
+	workWG.Wait()
+
+	return nil
+}
+
+// ProcessOrderedEvents is ProcessEvents for results that depend on the order
+// of events.  The value returned by process for each event is passed to
+// consume on the calling goroutine, in the order of a serial run over the
+// files.  Only a few events per worker are in flight at a time, so consume
+// can keep state over the sequence of events without holding all of them.
+func ProcessOrderedEvents(filenames []string, nWorkers int, process func(worker int, event *proio.Event) interface{}, consume func(result interface{})) error {
+	if nWorkers < 1 {
+		nWorkers = 1
+	}
+
+	readers, err := openReaders(filenames)
+	if err != nil {
+		return err
+	}
+
+	type job struct {
+		seq   int
+		event *proio.Event
+	}
+	type result struct {
+		seq   int
+		value interface{}
+	}
+	window := make(chan struct{}, 4*nWorkers)
+	jobs := make(chan job, nWorkers)
+	results := make(chan result, cap(window))
+
+	go func() {
+		seq := 0
+		for _, reader := range readers {
+			for event := range reader.ScanEvents() {
+				window <- struct{}{}
+				jobs <- job{seq, event}
+				seq++
+			}
+			reader.Close()
+		}
+		close(jobs)
+	}()
+
+	var workWG sync.WaitGroup
+	for worker := 0; worker < nWorkers; worker++ {
+		workWG.Add(1)
+		go func(worker int) {
+			defer workWG.Done()
+			for j := range jobs {
+				results <- result{j.seq, process(worker, j.event)}
+			}
+		}(worker)
+	}
+	go func() {
+		workWG.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]interface{})
+	next := 0
+	for r := range results {
+		pending[r.seq] = r.value
+		for value, ok := pending[next]; ok; value, ok = pending[next] {
+			delete(pending, next)
+			consume(value)
+			next++
+			<-window
+		}
+	}
+
+	return nil
+}
+
+func openReaders(filenames []string) ([]*proio.Reader, error) {
+	readers := make([]*proio.Reader, len(filenames))
+	for i, filename := range filenames {
+		reader, err := proio.Open(filename)
+		if err != nil {
+			for _, reader := range readers[:i] {
+				reader.Close()
+			}
+			return nil, err
+		}
+		readers[i] = reader
+	}
+	return readers, nil
+}
+
+// NewFlagWorkers registers the -workers command-line flag.
+func NewFlagWorkers() *int {
+	return flag.Int("workers", runtime.NumCPU(), "number of goroutines processing events")
+}
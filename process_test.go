@@ -0,0 +1,119 @@
+package eicplot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"go-hep.org/x/hep/hbook"
+)
+
+// writeTestFile writes n events, each with one particle whose Pdg code runs
+// over a range extending past both ends of the test histogram.
+func writeTestFile(t *testing.T, filename string, n, offset int) {
+	writer, err := proio.Create(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < n; i++ {
+		pdg := int32((i+offset)%30 - 5)
+		event := proio.NewEvent()
+		event.AddEntry("GenStable", &eic.Particle{Pdg: &pdg})
+		if err := writer.Push(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func fillTestHist(t *testing.T, filenames []string, nWorkers int) *hbook.H1D {
+	workerHists := make([][]*hbook.H1D, nWorkers)
+	for i := range workerHists {
+		workerHists[i] = []*hbook.H1D{hbook.NewH1D(20, 0, 20)}
+	}
+	err := ProcessEvents(filenames, nWorkers, func(worker int, event *proio.Event) {
+		for _, id := range event.TaggedEntries("GenStable") {
+			part := event.GetEntry(id).(*eic.Particle)
+			// weights of 1, 1.5 and 2 keep the sums exact in any order
+			workerHists[worker][0].Fill(float64(part.GetPdg())+0.5, 1+float64((part.GetPdg()+5)%3)/2)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return MergeH1DSets(workerHists)[0]
+}
+
+func TestProcessEventsWorkers(t *testing.T) {
+	dir := t.TempDir()
+	filenames := []string{filepath.Join(dir, "a.proio"), filepath.Join(dir, "b.proio")}
+	writeTestFile(t, filenames[0], 500, 0)
+	writeTestFile(t, filenames[1], 300, 7)
+
+	serial := fillTestHist(t, filenames, 1)
+	if n := serial.Entries(); n != 800 {
+		t.Fatalf("serial run has %v entries, want 800", n)
+	}
+
+	for _, nWorkers := range []int{2, 4, 8} {
+		h := fillTestHist(t, filenames, nWorkers)
+		if h.Entries() != serial.Entries() || h.SumW() != serial.SumW() || h.SumW2() != serial.SumW2() {
+			t.Errorf("%v workers: entries, sumw, sumw2 = %v, %v, %v, want %v, %v, %v", nWorkers,
+				h.Entries(), h.SumW(), h.SumW2(), serial.Entries(), serial.SumW(), serial.SumW2())
+		}
+		for i := range serial.Binning.Bins {
+			got, want := &h.Binning.Bins[i], &serial.Binning.Bins[i]
+			if got.Entries() != want.Entries() || got.SumW() != want.SumW() || got.SumW2() != want.SumW2() {
+				t.Errorf("%v workers, bin %v: entries, sumw, sumw2 = %v, %v, %v, want %v, %v, %v", nWorkers, i,
+					got.Entries(), got.SumW(), got.SumW2(), want.Entries(), want.SumW(), want.SumW2())
+			}
+		}
+		for i := range serial.Binning.Outflows {
+			got, want := &h.Binning.Outflows[i], &serial.Binning.Outflows[i]
+			if got.SumW() != want.SumW() || got.SumW2() != want.SumW2() {
+				t.Errorf("%v workers, outflow %v: sumw, sumw2 = %v, %v, want %v, %v", nWorkers, i,
+					got.SumW(), got.SumW2(), want.SumW(), want.SumW2())
+			}
+		}
+	}
+}
+
+func TestProcessOrderedEvents(t *testing.T) {
+	dir := t.TempDir()
+	filenames := []string{filepath.Join(dir, "a.proio"), filepath.Join(dir, "b.proio")}
+	writeTestFile(t, filenames[0], 500, 0)
+	writeTestFile(t, filenames[1], 300, 7)
+
+	var want []int32
+	for i := 0; i < 500; i++ {
+		want = append(want, int32(i%30-5))
+	}
+	for i := 0; i < 300; i++ {
+		want = append(want, int32((i+7)%30-5))
+	}
+
+	for _, nWorkers := range []int{1, 8} {
+		var got []int32
+		err := ProcessOrderedEvents(filenames, nWorkers, func(worker int, event *proio.Event) interface{} {
+			return event.GetEntry(event.TaggedEntries("GenStable")[0]).(*eic.Particle).GetPdg()
+		}, func(result interface{}) {
+			got = append(got, result.(int32))
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(got) != len(want) {
+			t.Fatalf("%v workers: consumed %v events, want %v", nWorkers, len(got), len(want))
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%v workers: event %v consumed out of order", nWorkers, i)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package eicplot
+
+import (
+	"flag"
+	"image/color"
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// RatioFigure draws a main plot above a ratio panel that shares its x axis.
+type RatioFigure struct {
+	Main, Ratio *plot.Plot
+
+	// RatioHeight is the fraction of the figure height taken by the ratio
+	// panel.
+	RatioHeight float64
+}
+
+// NewRatioFigure moves the x axis label of main to a new ratio panel, which
+// has a reference line at 1.
+func NewRatioFigure(main *plot.Plot) *RatioFigure {
+	ratio, _ := plot.New()
+	ratio.X.Label.Text = main.X.Label.Text
+	ratio.X.Tick.Marker = main.X.Tick.Marker
+	ratio.Y.Label.Text = "ratio"
+	ratio.Y.Tick.Marker = PreciseTicks{NSuggestedTicks: 3}
+	main.X.Label.Text = ""
+
+	one := plotter.NewFunction(func(float64) float64 { return 1 })
+	one.Color = color.Gray{Y: 128}
+	one.Dashes = []vg.Length{vg.Points(4), vg.Points(2)}
+	ratio.Add(one)
+
+	return &RatioFigure{Main: main, Ratio: ratio, RatioHeight: 0.3}
+}
+
+// AddRatio draws points in the ratio panel in the given style.
+func (f *RatioFigure) AddRatio(points plotutil.ErrorPoints, style SeriesStyle) {
+	if len(points.XYs) == 0 {
+		return
+	}
+	xerr, _ := plotter.NewXErrorBars(points)
+	yerr, _ := plotter.NewYErrorBars(points)
+	scatter, _ := plotter.NewScatter(points)
+
+	lineStyle := style.LineStyle()
+	lineStyle.Dashes = nil
+	xerr.LineStyle = lineStyle
+	yerr.LineStyle = lineStyle
+	scatter.GlyphStyle = style.GlyphStyle()
+	f.Ratio.Add(xerr, yerr, scatter)
+}
+
+// AddFileRatios divides each of series, which holds nPerFile consecutive
+// series for each input file, by the series in the same position for input
+// file ref.  Series are styled by their index.
+func (f *RatioFigure) AddFileRatios(series []plotutil.ErrorPoints, nPerFile, ref int) {
+	for i, points := range series {
+		if i/nPerFile == ref {
+			continue
+		}
+		f.AddRatio(RatioPoints(points, series[ref*nPerFile+i%nPerFile]), Style(i))
+	}
+}
+
+func (f *RatioFigure) Draw(c draw.Canvas) {
+	xMin := math.Min(f.Main.X.Min, f.Ratio.X.Min)
+	xMax := math.Max(f.Main.X.Max, f.Ratio.X.Max)
+	f.Main.X.Min, f.Ratio.X.Min = xMin, xMin
+	f.Main.X.Max, f.Ratio.X.Max = xMax, xMax
+	f.Main.X.Tick.Marker = unlabelledTicks{f.Main.X.Tick.Marker}
+
+	ratioHeight := vg.Length(f.RatioHeight) * (c.Max.Y - c.Min.Y)
+	mainCanvas := draw.Crop(c, 0, 0, ratioHeight, 0)
+	ratioCanvas := draw.Crop(c, 0, 0, 0, ratioHeight-(c.Max.Y-c.Min.Y))
+
+	// line up the left edges of the data areas
+	dx := f.Main.DataCanvas(mainCanvas).Min.X - f.Ratio.DataCanvas(ratioCanvas).Min.X
+	if dx > 0 {
+		ratioCanvas = draw.Crop(ratioCanvas, dx, 0, 0, 0)
+	} else {
+		mainCanvas = draw.Crop(mainCanvas, -dx, 0, 0, 0)
+	}
+
+	f.Main.Draw(mainCanvas)
+	f.Ratio.Draw(ratioCanvas)
+}
+
+// Save writes the figure in the format implied by the extension of filename.
+func (f *RatioFigure) Save(w, h vg.Length, filename string) error {
+	c, err := NewCanvas(filename, w, h)
+	if err != nil {
+		return err
+	}
+	f.Draw(draw.New(c))
+	return SaveCanvas(c, filename)
+}
+
+// unlabelledTicks keeps the tick positions of a shared axis without drawing
+// the labels a second time.
+type unlabelledTicks struct {
+	plot.Ticker
+}
+
+func (t unlabelledTicks) Ticks(min, max float64) []plot.Tick {
+	ticks := t.Ticker.Ticks(min, max)
+	for i := range ticks {
+		ticks[i].Label = ""
+	}
+	return ticks
+}
+
+// H1DErrorPoints returns the bin contents of h at the bin centers, with y
+// errors from the sum of squared weights and x errors spanning the bins.
+func H1DErrorPoints(h *hbook.H1D) plotutil.ErrorPoints {
+	n := h.Len()
+	points := make(plotter.XYs, n)
+	xErrors := make(plotter.XErrors, n)
+	yErrors := make(plotter.YErrors, n)
+	for i, bin := range h.Binning.Bins {
+		points[i].X = bin.XMid()
+		points[i].Y = bin.SumW()
+		xErrors[i].Low = bin.XWidth() / 2
+		xErrors[i].High = bin.XWidth() / 2
+		yErrors[i].Low = math.Sqrt(bin.SumW2())
+		yErrors[i].High = math.Sqrt(bin.SumW2())
+	}
+	return plotutil.ErrorPoints{XYs: points, XErrors: xErrors, YErrors: yErrors}
+}
+
+// RatioPoints divides num by den point by point, which must share x values.
+// Errors are propagated in quadrature assuming the two are uncorrelated, with
+// asymmetric errors symmetrized.  Points where den is zero are left out.
+func RatioPoints(num, den plotutil.ErrorPoints) plotutil.ErrorPoints {
+	var ratio plotutil.ErrorPoints
+	for i := range num.XYs {
+		d := den.XYs[i].Y
+		if d == 0 {
+			continue
+		}
+		n := num.XYs[i].Y
+		r := n / d
+
+		nErr := (num.YErrors[i].Low + num.YErrors[i].High) / 2
+		dErr := (den.YErrors[i].Low + den.YErrors[i].High) / 2
+		rErr := math.Sqrt(nErr*nErr+r*r*dErr*dErr) / math.Abs(d)
+
+		ratio.XYs = append(ratio.XYs, struct{ X, Y float64 }{num.XYs[i].X, r})
+		ratio.XErrors = append(ratio.XErrors, num.XErrors[i])
+		ratio.YErrors = append(ratio.YErrors, struct{ Low, High float64 }{rErr, rErr})
+	}
+	return ratio
+}
+
+// NewFlagRatio registers the -ratio command-line flag.
+func NewFlagRatio() *bool {
+	return flag.Bool("ratio", false, "draw ratios to the reference input file in a panel below the plot")
+}
+
+// NewFlagReference registers the -ref command-line flag.
+func NewFlagReference() *int {
+	return flag.Int("ref", 0, "index of the reference input file for ratios and comparisons")
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"go-hep.org/x/hep/hbook"
+	"go-hep.org/x/hep/hbook/rootcnv"
+	"go-hep.org/x/hep/hbook/yodacnv"
+	"go-hep.org/x/hep/hplot"
+	"go-hep.org/x/hep/rootio"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/vg"
+
+	"github.com/decibelcooper/eicplot"
+)
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <histogram-files>...
+
+Histogram files are ROOT or YODA files saved with the -hists option of the
+other commands.  If any selected histogram is 2-dimensional, the first of
+these is drawn as a heatmap and the others are skipped.  Otherwise, pairs
+of histograms in the same file named <name>_pass and <name>_total are drawn
+as efficiencies, and all remaining histograms are overlaid.  With several
+files, default legend entries are prefixed with the base name of the file.
+The canvas size applies to heatmaps; other plots are 6in by 4in.
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+func main() {
+	names := &eicplot.StringArrayFlags{}
+	var (
+		title    = flag.String("title", "", "plot title")
+		xLabel   = flag.String("xlabel", "", "x axis label")
+		yLabel   = flag.String("ylabel", "", "y axis label")
+		zLabel   = flag.String("zlabel", "", "heatmap color bar label")
+		logY     = flag.Bool("logy", false, "use a logarithmic y axis for 1-dimensional histograms")
+		zMin     = flag.Float64("zmin", 0, "minimum of the heatmap color map")
+		zMax     = flag.Float64("zmax", 0.1, "maximum of the heatmap color map")
+		palName  = flag.String("palette", "blackbody", "heatmap palette (blackbody or bluered)")
+		interval eicplot.IntervalMethod
+		output   = flag.String("output", "out.png", "output file")
+		labels   = eicplot.NewFlagLabels()
+		legend   = eicplot.NewFlagLegend()
+	)
+	width, height := eicplot.NewFlagCanvasSize(670, 400)
+	flag.Var(names, "hist", "name of a histogram to draw (may be repeated; default all)")
+	flag.Var(&interval, "interval", "efficiency interval method (clopper-pearson, wilson or bayesian)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+
+	// histograms are paired within each file, since files written by the
+	// same command share names
+	var (
+		effs                 []*eicplot.Efficiency
+		others               []*hbook.H1D
+		effNames, otherNames []string
+		h2ds                 []*hbook.H2D
+		h2dNames             []string
+	)
+	for _, filename := range flag.Args() {
+		objs, err := readHists(filename)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		prefix := ""
+		if flag.NArg() > 1 {
+			prefix = eicplot.FileTag(filename) + "/"
+		}
+
+		var h1ds []*hbook.H1D
+		for _, obj := range objs {
+			if !selected(obj.Name(), names.Array) {
+				continue
+			}
+			switch h := obj.(type) {
+			case *hbook.H1D:
+				h1ds = append(h1ds, h)
+			case *hbook.H2D:
+				h2ds = append(h2ds, h)
+				h2dNames = append(h2dNames, prefix+h.Name())
+			}
+		}
+
+		fileEffs, fileOthers := pairEfficiencies(h1ds)
+		for _, eff := range fileEffs {
+			effs = append(effs, eff)
+			effNames = append(effNames, prefix+strings.TrimSuffix(eff.Pass.Name(), "_pass"))
+		}
+		for _, h := range fileOthers {
+			others = append(others, h)
+			otherNames = append(otherNames, prefix+h.Name())
+		}
+	}
+
+	if len(h2ds) > 0 {
+		if len(h2ds) > 1 {
+			log.Printf("drawing %v; skipping %v (select one with -hist)", h2dNames[0], strings.Join(h2dNames[1:], ", "))
+		}
+
+		var colorMap palette.ColorMap
+		switch *palName {
+		case "bluered":
+			colorMap = moreland.SmoothBlueRed()
+		case "blackbody":
+			colorMap = moreland.ExtendedBlackBody()
+		default:
+			log.Fatalf("unknown palette %q", *palName)
+		}
+
+		fig := eicplot.NewHeatmapFigure(h2ds[0].GridXYZ(), colorMap, *zMin, *zMax)
+		fig.Title = *title
+		fig.XLabel = *xLabel
+		fig.YLabel = *yLabel
+		fig.ZLabel = *zLabel
+		if err := fig.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	p, _ := plot.New()
+	p.Title.Text = *title
+	p.X.Label.Text = *xLabel
+	p.Y.Label.Text = *yLabel
+	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+
+	if *logY {
+		p.Y.Tick.Marker = eicplot.LogTicks{}
+		p.Y.Scale = eicplot.LogScale{}
+	}
+
+	for i, eff := range effs {
+		eff.Interval = interval
+		plotters, thumb := eff.Plotters(eicplot.Style(i))
+		p.Add(plotters...)
+		legend.Add(p, eicplot.Label(labels, i, effNames[i]), thumb)
+	}
+
+	for i, hist := range others {
+		h := hplot.NewH1D(hist)
+		h.FillColor = nil
+		h.LineStyle = eicplot.Style(len(effs) + i).LineStyle()
+		h.Infos.Style = hplot.HInfoNone
+
+		p.Add(h)
+		legend.Add(p, eicplot.Label(labels, len(effs)+i, otherNames[i]), h)
+	}
+
+	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+}
+
+func readHists(filename string) ([]hbook.Object, error) {
+	if filepath.Ext(filename) == ".yoda" {
+		r, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer r.Close()
+		return yodacnv.Read(r)
+	}
+
+	f, err := rootio.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var objs []hbook.Object
+	for _, key := range f.Keys() {
+		obj, err := key.Object()
+		if err != nil {
+			return nil, err
+		}
+
+		switch h := obj.(type) {
+		case rootio.H2:
+			h2d, err := rootcnv.H2D(h)
+			if err != nil {
+				return nil, err
+			}
+			h2d.Annotation()["name"] = key.Name()
+			objs = append(objs, h2d)
+		case rootio.H1:
+			h1d, err := rootcnv.H1D(h)
+			if err != nil {
+				return nil, err
+			}
+			h1d.Annotation()["name"] = key.Name()
+			objs = append(objs, h1d)
+		}
+	}
+	return objs, nil
+}
+
+func selected(name string, names []string) bool {
+	if len(names) == 0 {
+		return true
+	}
+	for _, n := range names {
+		if n == name || n+"_pass" == name || n+"_total" == name {
+			return true
+		}
+	}
+	return false
+}
+
+// pairEfficiencies finds histograms named <name>_pass and <name>_total, as
+// written by trackeff, and returns them as efficiencies along with the
+// unpaired histograms.
+func pairEfficiencies(h1ds []*hbook.H1D) ([]*eicplot.Efficiency, []*hbook.H1D) {
+	byName := make(map[string]*hbook.H1D)
+	for _, h := range h1ds {
+		byName[h.Name()] = h
+	}
+
+	var effNames []string
+	paired := make(map[string]bool)
+	for _, h := range h1ds {
+		name := strings.TrimSuffix(h.Name(), "_pass")
+		if name == h.Name() {
+			continue
+		}
+		if _, ok := byName[name+"_total"]; ok {
+			effNames = append(effNames, name)
+			paired[name+"_pass"] = true
+			paired[name+"_total"] = true
+		}
+	}
+	sort.Strings(effNames)
+
+	var effs []*eicplot.Efficiency
+	for _, name := range effNames {
+		effs = append(effs, &eicplot.Efficiency{
+			Pass:  byName[name+"_pass"],
+			Total: byName[name+"_total"],
+			CL:    eicplot.OneSigma,
+		})
+	}
+
+	var others []*hbook.H1D
+	for _, h := range h1ds {
+		if !paired[h.Name()] {
+			others = append(others, h)
+		}
+	}
+	return effs, others
+}
@@ -0,0 +1,145 @@
+package eicplot
+
+import (
+	"math"
+
+	"go-hep.org/x/hep/hbook"
+)
+
+// ResGrid estimates the resolution of values filled in bins of two
+// variables.
+type ResGrid struct {
+	Estimator Estimator
+	NSigma    float64
+	// Empty is the value of cells with fewer than 3 values.
+	Empty float64
+
+	hCount, hV, hV2 *hbook.H2D
+	// values are only kept for estimators that need them.
+	values         [][]float64
+	zs, errs       []float64
+	nBinsX, nBinsY int
+	xLow, xHigh    float64
+	yLow, yHigh    float64
+}
+
+func NewResGrid(nBinsX int, xLow, xHigh float64, nBinsY int, yLow, yHigh float64) *ResGrid {
+	return &ResGrid{
+		Estimator: RMS,
+		NSigma:    2.5,
+		Empty:     1,
+		hCount:    hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
+		hV:        hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
+		hV2:       hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
+		values:    make([][]float64, nBinsX*nBinsY),
+		nBinsX:    nBinsX,
+		nBinsY:    nBinsY,
+		xLow:      xLow,
+		xHigh:     xHigh,
+		yLow:      yLow,
+		yHigh:     yHigh,
+	}
+}
+
+func (g *ResGrid) Fill(x, y, z float64) {
+	if x < g.xLow || x >= g.xHigh || y < g.yLow || y >= g.yHigh {
+		return
+	}
+	g.hCount.Fill(x, y, 1)
+	g.hV.Fill(x, y, z)
+	g.hV2.Fill(x, y, z*z)
+	g.zs, g.errs = nil, nil
+
+	if g.Estimator.NeedsValues() {
+		i := int(float64(g.nBinsX) * (x - g.xLow) / (g.xHigh - g.xLow))
+		j := int(float64(g.nBinsY) * (y - g.yLow) / (g.yHigh - g.yLow))
+		g.values[j*g.nBinsX+i] = append(g.values[j*g.nBinsX+i], z)
+	}
+}
+
+// Merge adds the entries filled into other, which must have identical
+// binning.
+func (g *ResGrid) Merge(other *ResGrid) {
+	MergeH2D(g.hCount, other.hCount)
+	MergeH2D(g.hV, other.hV)
+	MergeH2D(g.hV2, other.hV2)
+	for i := range g.values {
+		g.values[i] = append(g.values[i], other.values[i]...)
+	}
+	g.zs, g.errs = nil, nil
+}
+
+// H2D returns a histogram with the content of each cell set to Z, leaving out
+// cells that are NaN.
+func (g *ResGrid) H2D() *hbook.H2D {
+	h := hbook.NewH2D(g.nBinsX, g.xLow, g.xHigh, g.nBinsY, g.yLow, g.yHigh)
+	for i := 0; i < g.nBinsX; i++ {
+		for j := 0; j < g.nBinsY; j++ {
+			if z := g.Z(i, j); !math.IsNaN(z) {
+				h.Fill(g.X(i), g.Y(j), z)
+			}
+		}
+	}
+	return h
+}
+
+func (g *ResGrid) Dims() (int, int) {
+	return g.nBinsX, g.nBinsY
+}
+
+// Z estimates the resolution of every cell on the first call after filling,
+// and returns the stored estimate afterwards.
+func (g *ResGrid) Z(i, j int) float64 {
+	g.estimate()
+	return g.zs[j*g.nBinsX+i]
+}
+
+// Err is the standard error of Z, as given by Estimator.Estimate.  It is NaN
+// for cells with fewer than 3 values.
+func (g *ResGrid) Err(i, j int) float64 {
+	g.estimate()
+	return g.errs[j*g.nBinsX+i]
+}
+
+func (g *ResGrid) estimate() {
+	if g.zs != nil {
+		return
+	}
+	g.zs = make([]float64, len(g.values))
+	g.errs = make([]float64, len(g.values))
+	for k := range g.zs {
+		g.zs[k], g.errs[k] = g.estimateCell(k%g.nBinsX, k/g.nBinsX)
+	}
+}
+
+func (g *ResGrid) estimateCell(i, j int) (z, err float64) {
+	n := g.hCount.GridXYZ().Z(i, j)
+	if n < 3 {
+		return g.Empty, math.NaN()
+	}
+	if !g.Estimator.NeedsValues() {
+		mean := g.hV.GridXYZ().Z(i, j) / n
+		stddev := math.Sqrt(math.Max(g.hV2.GridXYZ().Z(i, j)/n-mean*mean, 0))
+		return stddev, stddev / math.Sqrt(2*n)
+	}
+	return g.Estimator.Estimate(g.values[j*g.nBinsX+i], g.NSigma)
+}
+
+// Counts returns the number of values filled into each cell.
+func (g *ResGrid) Counts() *hbook.H2D {
+	return g.hCount
+}
+
+// XRange returns the edges of column i.
+func (g *ResGrid) XRange(i int) (low, high float64) {
+	width := (g.xHigh - g.xLow) / float64(g.nBinsX)
+	return g.xLow + float64(i)*width, g.xLow + float64(i+1)*width
+}
+
+func (g *ResGrid) X(i int) float64 {
+	return g.hCount.GridXYZ().X(i)
+}
+
+func (g *ResGrid) Y(j int) float64 {
+	return g.hCount.GridXYZ().Y(j)
+}
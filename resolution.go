@@ -0,0 +1,181 @@
+package eicplot
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"go-hep.org/x/hep/fit"
+	"go-hep.org/x/hep/hbook"
+	"gonum.org/v1/gonum/stat"
+)
+
+// Estimator selects how the resolution is estimated from the values in a cell.
+type Estimator int
+
+const (
+	// RMS is the standard deviation of all values.
+	RMS Estimator = iota
+	// TruncatedRMS iteratively discards values outside of NSigma standard
+	// deviations from the mean.
+	TruncatedRMS
+	// GaussCore is the width of a Gaussian fit to the values within NSigma
+	// truncated standard deviations of the truncated mean.
+	GaussCore
+	// Central68 is half the width of the central interval containing 68% of
+	// the values.
+	Central68
+)
+
+var estimatorNames = map[Estimator]string{
+	RMS:          "rms",
+	TruncatedRMS: "truncated",
+	GaussCore:    "gauss",
+	Central68:    "central68",
+}
+
+func (e *Estimator) Set(valueStr string) error {
+	for estimator, name := range estimatorNames {
+		if name == valueStr {
+			*e = estimator
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown resolution estimator %q", valueStr)
+}
+
+func (e *Estimator) String() string {
+	return estimatorNames[*e]
+}
+
+// NeedsValues reports whether the estimate needs the individual values, rather
+// than their sums.
+func (e Estimator) NeedsValues() bool {
+	return e != RMS
+}
+
+const maxTruncIter = 20
+
+// Estimate returns the resolution of values and its standard error.  The
+// error assumes Gaussian values for RMS, and is NaN for TruncatedRMS and
+// GaussCore.  It sorts a copy of values first, so that the result does not
+// depend on the order of filling.
+func (e Estimator) Estimate(values []float64, nSigma float64) (res, err float64) {
+	if e == Central68 {
+		return Central68Width(values)
+	}
+
+	values = append([]float64(nil), values...)
+	sort.Float64s(values)
+
+	switch e {
+	case TruncatedRMS:
+		_, stddev := truncMeanStdDev(values, nSigma)
+		return stddev, math.NaN()
+	case GaussCore:
+		return gaussCoreWidth(values, nSigma), math.NaN()
+	default:
+		_, stddev := popMeanStdDev(values)
+		return stddev, stddev / math.Sqrt(2*float64(len(values)))
+	}
+}
+
+// central68Low is the lower probability of the central 68% interval, which
+// is one standard deviation for Gaussian values.
+const central68Low = 0.5 - 0.6827/2
+
+// Central68Width returns half the width of the central interval containing
+// 68% of values, which is insensitive to tails, and its standard error.  The
+// error combines the errors of the two quantiles, each estimated from the
+// order statistics within one binomial standard deviation of it, with their
+// asymptotic correlation.  Both are NaN for fewer than 3 values.
+func Central68Width(values []float64) (halfWidth, err float64) {
+	if len(values) < 3 {
+		return math.NaN(), math.NaN()
+	}
+	values = append([]float64(nil), values...)
+	sort.Float64s(values)
+
+	p1, p2 := central68Low, 1-central68Low
+	low := stat.Quantile(p1, stat.Empirical, values, nil)
+	high := stat.Quantile(p2, stat.Empirical, values, nil)
+
+	n := float64(len(values))
+	quantileErr := func(p float64) float64 {
+		d := math.Sqrt(p * (1 - p) / n)
+		return (stat.Quantile(math.Min(p+d, 1), stat.Empirical, values, nil) -
+			stat.Quantile(math.Max(p-d, 0), stat.Empirical, values, nil)) / 2
+	}
+	err1, err2 := quantileErr(p1), quantileErr(p2)
+	rho := p1 * (1 - p2) / math.Sqrt(p1*(1-p1)*p2*(1-p2))
+	return (high - low) / 2, math.Sqrt(math.Max(err1*err1+err2*err2-2*rho*err1*err2, 0)) / 2
+}
+
+func truncMeanStdDev(values []float64, nSigma float64) (mean, stddev float64) {
+	mean, stddev = popMeanStdDev(values)
+	kept := values
+	for iter := 0; iter < maxTruncIter; iter++ {
+		var next []float64
+		for _, v := range values {
+			if math.Abs(v-mean) <= nSigma*stddev {
+				next = append(next, v)
+			}
+		}
+		if len(next) == len(kept) || len(next) < 3 {
+			break
+		}
+		kept = next
+		mean, stddev = popMeanStdDev(kept)
+	}
+	return mean, stddev
+}
+
+// gaussCoreWidth falls back to the truncated standard deviation if the fit
+// fails.
+func gaussCoreWidth(values []float64, nSigma float64) float64 {
+	mean, stddev := truncMeanStdDev(values, nSigma)
+	if stddev == 0 {
+		return 0
+	}
+
+	nBins := int(math.Max(10, math.Min(50, math.Sqrt(float64(len(values))))))
+	hist := hbook.NewH1D(nBins, mean-nSigma*stddev, mean+nSigma*stddev)
+	for _, v := range values {
+		hist.Fill(v, 1)
+	}
+
+	amplitude := 0.
+	for i := 0; i < hist.Len(); i++ {
+		amplitude = math.Max(amplitude, hist.Value(i))
+	}
+
+	res, err := fit.H1D(
+		hist,
+		fit.Func1D{
+			F: func(x float64, ps []float64) float64 {
+				return ps[0] * math.Exp(-0.5*math.Pow((x-ps[1])/ps[2], 2))
+			},
+			Ps: []float64{amplitude, mean, stddev},
+		},
+		nil, nil,
+	)
+	if err != nil {
+		return stddev
+	}
+	width := math.Abs(res.X[2])
+	if width == 0 || width > nSigma*stddev {
+		return stddev
+	}
+	return width
+}
+
+func popMeanStdDev(values []float64) (mean, stddev float64) {
+	var sum, sum2 float64
+	for _, v := range values {
+		sum += v
+		sum2 += v * v
+	}
+	n := float64(len(values))
+	mean = sum / n
+	return mean, math.Sqrt(math.Max(sum2/n-mean*mean, 0))
+}
@@ -0,0 +1,29 @@
+package eicplot
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+func TestCentral68Width(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	values := make([]float64, 10000)
+	for i := range values {
+		values[i] = 2 * rng.NormFloat64()
+	}
+
+	// for Gaussian values the half width is the standard deviation, with an
+	// asymptotic error of 0.962 sigma/sqrt(n)
+	halfWidth, err := Central68Width(values)
+	if math.Abs(halfWidth-2) > 0.05 {
+		t.Errorf("half width = %v, want about 2", halfWidth)
+	}
+	if wantErr := 0.962 * 2 / 100; math.Abs(err-wantErr) > 0.2*wantErr {
+		t.Errorf("error = %v, want about %v", err, wantErr)
+	}
+
+	if halfWidth, err := Central68Width(values[:2]); !math.IsNaN(halfWidth) || !math.IsNaN(err) {
+		t.Errorf("Central68Width of 2 values = %v, %v, want NaN", halfWidth, err)
+	}
+}
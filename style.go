@@ -0,0 +1,68 @@
+package eicplot
+
+import (
+	"image/color"
+
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/vg"
+	"gonum.org/v1/plot/vg/draw"
+)
+
+// seriesColors is the Okabe-Ito colorblind-safe palette, without yellow,
+// which is hard to see on white.
+var seriesColors = []color.Color{
+	color.RGBA{A: 255},
+	color.RGBA{R: 0, G: 114, B: 178, A: 255},
+	color.RGBA{R: 213, G: 94, B: 0, A: 255},
+	color.RGBA{R: 0, G: 158, B: 115, A: 255},
+	color.RGBA{R: 204, G: 121, B: 167, A: 255},
+	color.RGBA{R: 230, G: 159, B: 0, A: 255},
+	color.RGBA{R: 86, G: 180, B: 233, A: 255},
+}
+
+var seriesShapes = []draw.GlyphDrawer{
+	draw.CircleGlyph{},
+	draw.SquareGlyph{},
+	draw.TriangleGlyph{},
+	draw.CrossGlyph{},
+	draw.RingGlyph{},
+	draw.BoxGlyph{},
+	draw.PyramidGlyph{},
+	draw.PlusGlyph{},
+}
+
+// SeriesStyle distinguishes one of several overlaid series.
+type SeriesStyle struct {
+	Color  color.Color
+	Dashes []vg.Length
+	Shape  draw.GlyphDrawer
+}
+
+// Style returns the style of the i'th overlaid series.  Colors and glyph
+// shapes cycle with coprime periods, and each pass through the colors gets a
+// longer dash pattern, so that any number of series are distinct.
+func Style(i int) SeriesStyle {
+	style := SeriesStyle{
+		Color: seriesColors[i%len(seriesColors)],
+		Shape: seriesShapes[i%len(seriesShapes)],
+	}
+	if pass := i / len(seriesColors); pass > 0 {
+		style.Dashes = []vg.Length{vg.Points(float64(2 + 2*pass)), vg.Points(2)}
+	}
+	return style
+}
+
+func (s SeriesStyle) LineStyle() draw.LineStyle {
+	lineStyle := plotter.DefaultLineStyle
+	lineStyle.Color = s.Color
+	lineStyle.Dashes = s.Dashes
+	return lineStyle
+}
+
+func (s SeriesStyle) GlyphStyle() draw.GlyphStyle {
+	return draw.GlyphStyle{
+		Color:  s.Color,
+		Radius: vg.Points(2),
+		Shape:  s.Shape,
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
+)
+
+var (
+	pTMin     = flag.Float64("minpt", 0.5, "minimum transverse momentum")
+	pTMax     = flag.Float64("maxpt", 30, "maximum transverse momentum")
+	etaLimit  = flag.Float64("etalimit", 4, "maximum absolute value of eta")
+	rateLimit = flag.Float64("ratelimit", 0.05, "maximum wrong-sign fraction in the color map")
+	nBinsPT   = flag.Int("nbinspt", 10, "number of bins in transverse momentum")
+	nBinsEta  = flag.Int("nbinseta", 10, "number of bins in eta")
+	curves    = flag.Bool("curves", false, "plot the wrong-sign fraction vs p_T for each eta bin instead of the map")
+	title     = flag.String("title", "", "plot title")
+	output    = flag.String("output", "out.png", "output file")
+	nWorkers  = eicplot.NewFlagWorkers()
+	histsOut  = eicplot.NewFlagHistFile()
+	matcher   = truth.NewFlagMatcher()
+	labels    = eicplot.NewFlagLabels()
+	legend    = eicplot.NewFlagLegend()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+	interval eicplot.IntervalMethod
+)
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Var(&interval, "interval", "binomial interval method (clopper-pearson, wilson or bayesian)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+
+	flipGrids := make([]*eicplot.EfficiencyGrid, *nWorkers)
+	for i := range flipGrids {
+		flipGrids[i] = eicplot.NewEfficiencyGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
+	}
+
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		flipGrid := flipGrids[worker]
+
+		ids := event.TaggedEntries("Reconstructed")
+		for _, id := range ids {
+			track, ok := event.GetEntry(id).(*eic.Track)
+			if !ok {
+				continue
+			}
+
+			part := matcher.Match(event, track).Particle
+			if part == nil || part.GetCharge() == 0 {
+				continue
+			}
+
+			if len(track.Segment) == 0 || track.Segment[0].Chargesign == nil {
+				continue
+			}
+
+			partP := kin.Vec3FromXYZF(part.GetP())
+			wrongSign := (track.Segment[0].GetChargesign() > 0) != (part.GetCharge() > 0)
+			flipGrid.Fill(partP.Eta(), partP.Pt(), wrongSign)
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	flipGrid := flipGrids[0]
+	for _, g := range flipGrids[1:] {
+		flipGrid.Merge(g)
+	}
+
+	flipGrid.Interval = interval
+	nBinsX, _ := flipGrid.Dims()
+
+	if *histsOut != "" {
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(eicplot.HistName("chargeflip"), "wrong-sign fraction vs eta and p_T", flipGrid.H2D())
+		for i := 0; i < nBinsX; i++ {
+			eff := flipGrid.SliceY(i)
+			name := eicplot.HistName("chargeflip", "eta"+eicplot.FormatCut(flipGrid.X(i)))
+			histFile.AddH1D(name+"_pass", "wrong-sign tracks vs p_T", eff.Pass)
+			histFile.AddH1D(name+"_total", "matched tracks vs p_T", eff.Total)
+		}
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *curves {
+		p, _ := plot.New()
+		p.Title.Text = *title
+		p.X.Label.Text = "p_T"
+		p.Y.Label.Text = "wrong-sign fraction"
+		p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+		p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+
+		for i := 0; i < nBinsX; i++ {
+			plotters, thumb := flipGrid.SliceY(i).Plotters(eicplot.Style(i))
+			p.Add(plotters...)
+
+			etaLow, etaHigh := flipGrid.XRange(i)
+			defaultLabel := fmt.Sprintf("%.3g < eta < %.3g", etaLow, etaHigh)
+			legend.Add(p, eicplot.Label(labels, i, defaultLabel), thumb)
+		}
+
+		if err := p.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fig := eicplot.NewHeatmapFigure(flipGrid, moreland.ExtendedBlackBody(), 0, *rateLimit)
+	fig.Title = *title
+	fig.XLabel = "eta"
+	fig.YLabel = "p_T"
+	fig.ZLabel = "wrong-sign fraction"
+	if err := fig.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
+}
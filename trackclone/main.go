@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
+)
+
+var (
+	pTMin     = flag.Float64("minpt", 0.5, "minimum transverse momentum")
+	pTMax     = flag.Float64("maxpt", 30, "maximum transverse momentum")
+	etaLimit  = flag.Float64("etalimit", 4, "maximum absolute value of eta")
+	rateLimit = flag.Float64("ratelimit", 0.1, "maximum clone rate in the color map")
+	nBinsPT   = flag.Int("nbinspt", 10, "number of bins in transverse momentum")
+	nBinsEta  = flag.Int("nbinseta", 20, "number of bins in eta")
+	vsPT      = flag.Bool("vspt", false, "plot the clone rate vs p_T instead of eta")
+	drawMap   = flag.Bool("map", false, "draw a map in eta and p_T instead of a curve")
+	title     = flag.String("title", "", "plot title")
+	output    = flag.String("output", "out.png", "output file")
+	nWorkers  = eicplot.NewFlagWorkers()
+	histsOut  = eicplot.NewFlagHistFile()
+	matcher   = truth.NewFlagMatcher()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+	interval eicplot.IntervalMethod
+)
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Var(&interval, "interval", "binomial interval method (clopper-pearson, wilson or bayesian)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+
+	grids := make([]*eicplot.EfficiencyGrid, *nWorkers)
+	nExtra := make([]int, *nWorkers)
+	for i := range grids {
+		grids[i] = eicplot.NewEfficiencyGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
+	}
+
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		grid := grids[worker]
+
+		nTracks := make(map[uint64]int)
+		for _, match := range matcher.MatchTagged(event, "Reconstructed") {
+			if match.Matched() {
+				nTracks[match.ParticleID]++
+			}
+		}
+
+		// a particle with more than one matched track has clones
+		for id, n := range nTracks {
+			part, ok := event.GetEntry(id).(*eic.Particle)
+			if !ok {
+				continue
+			}
+
+			partP := kin.Vec3FromXYZF(part.GetP())
+			grid.Fill(partP.Eta(), partP.Pt(), n > 1)
+			nExtra[worker] += n - 1
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	grid := grids[0]
+	for _, g := range grids[1:] {
+		grid.Merge(g)
+	}
+	extra := 0
+	for _, n := range nExtra {
+		extra += n
+	}
+
+	etaEff, ptEff := grid.ProjectX(), grid.ProjectY()
+	var nCloned, nMatched float64
+	for i := 0; i < etaEff.Len(); i++ {
+		nCloned += etaEff.Pass.Value(i)
+		nMatched += etaEff.Total.Value(i)
+	}
+	fmt.Printf("%v of %v matched particles in range have clones, %v extra tracks in total\n", nCloned, nMatched, extra)
+
+	if *histsOut != "" {
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(eicplot.HistName("clone"), "clone rate vs eta and p_T", grid.H2D())
+		histFile.AddH1D(eicplot.HistName("clone", "eta_pass"), "particles with clones vs eta", etaEff.Pass)
+		histFile.AddH1D(eicplot.HistName("clone", "eta_total"), "particles with matched tracks vs eta", etaEff.Total)
+		histFile.AddH1D(eicplot.HistName("clone", "pt_pass"), "particles with clones vs p_T", ptEff.Pass)
+		histFile.AddH1D(eicplot.HistName("clone", "pt_total"), "particles with matched tracks vs p_T", ptEff.Total)
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *drawMap {
+		fig := eicplot.NewHeatmapFigure(grid, moreland.ExtendedBlackBody(), 0, *rateLimit)
+		fig.Title = *title
+		fig.XLabel = "eta"
+		fig.YLabel = "p_T"
+		fig.ZLabel = "clone rate"
+		if err := fig.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	p, _ := plot.New()
+	p.Title.Text = *title
+	p.X.Label.Text = "eta"
+	eff := etaEff
+	if *vsPT {
+		p.X.Label.Text = "p_T"
+		eff = ptEff
+	}
+	p.Y.Label.Text = "clone rate"
+	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+
+	eff.Interval = interval
+	plotters, _ := eff.Plotters(eicplot.Style(0))
+	p.Add(plotters...)
+
+	if err := p.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/plotutil"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
+)
+
+var (
+	pTMin    = flag.Float64("minpt", 0.5, "minimum transverse momentum")
+	pTMax    = flag.Float64("maxpt", 30, "maximum transverse momentum")
+	etaLimit = flag.Float64("etalimit", 4, "maximum absolute value of eta")
+	resLimit = flag.Float64("reslimit", 200, "maximum DCA resolution in the color map (um)")
+	nBinsPT  = flag.Int("nbinspt", 10, "number of bins in transverse momentum")
+	nBinsEta = flag.Int("nbinseta", 10, "number of bins in eta")
+	curves   = flag.Bool("curves", false, "plot the resolution vs p_T for each eta bin instead of the map")
+	title    = flag.String("title", "", "plot title")
+	output   = flag.String("output", "out.png", "output file")
+	nWorkers = eicplot.NewFlagWorkers()
+	histsOut = eicplot.NewFlagHistFile()
+	matcher  = truth.NewFlagMatcher()
+	labels   = eicplot.NewFlagLabels()
+	legend   = eicplot.NewFlagLegend()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+	component Component
+)
+
+// Component selects the transverse or longitudinal distance of closest
+// approach.
+type Component int
+
+const (
+	Transverse Component = iota
+	Longitudinal
+)
+
+var componentNames = map[Component]string{
+	Transverse:   "xy",
+	Longitudinal: "z",
+}
+
+var componentLabels = map[Component]string{
+	Transverse:   "transverse",
+	Longitudinal: "longitudinal",
+}
+
+func (c *Component) Set(valueStr string) error {
+	for component, name := range componentNames {
+		if name == valueStr {
+			*c = component
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown DCA component %q", valueStr)
+}
+
+func (c *Component) String() string {
+	return componentNames[*c]
+}
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Var(&component, "dca", "DCA component (xy or z)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+
+	// the central 68% interval is insensitive to the tails from wrong hits
+	dcaGrids := make([]*eicplot.ResGrid, *nWorkers)
+	for i := range dcaGrids {
+		dcaGrids[i] = eicplot.NewResGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
+		dcaGrids[i].Estimator = eicplot.Central68
+		dcaGrids[i].Empty = math.NaN()
+	}
+
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		dcaGrid := dcaGrids[worker]
+
+		ids := event.TaggedEntries("Reconstructed")
+		for _, id := range ids {
+			track, ok := event.GetEntry(id).(*eic.Track)
+			if !ok {
+				continue
+			}
+
+			part := matcher.Match(event, track).Particle
+			if part == nil || part.Vertex == nil {
+				continue
+			}
+
+			if len(track.Segment) == 0 || track.Segment[0].Vertex == nil {
+				continue
+			}
+
+			partP := kin.Vec3FromXYZF(part.GetP())
+			seg := track.Segment[0]
+			dxy, dz := DCA(
+				kin.Vec3FromXYZTD(seg.Vertex),
+				kin.Vec3FromXYZD(seg.GetPoq()),
+				kin.Vec3FromXYZTD(part.Vertex),
+			)
+			dca := dxy
+			if component == Longitudinal {
+				dca = dz
+			}
+
+			// mm to um
+			dcaGrid.Fill(partP.Eta(), partP.Pt(), 1000*dca)
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	dcaGrid := dcaGrids[0]
+	for _, g := range dcaGrids[1:] {
+		dcaGrid.Merge(g)
+	}
+
+	zLabel := componentLabels[component] + " DCA resolution (um)"
+	if *histsOut != "" {
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(eicplot.HistName("dca", component.String()), zLabel+" vs eta and p_T", dcaGrid.H2D())
+		histFile.AddH2D(eicplot.HistName("dca", component.String(), "counts"), "matched tracks vs eta and p_T", dcaGrid.Counts())
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *curves {
+		p, _ := plot.New()
+		p.Title.Text = *title
+		p.X.Label.Text = "p_T"
+		p.Y.Label.Text = zLabel
+		p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+		p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+
+		nBinsX, _ := dcaGrid.Dims()
+		for i := 0; i < nBinsX; i++ {
+			points := ptCurve(dcaGrid, i)
+			if len(points.XYs) == 0 {
+				continue
+			}
+			style := eicplot.Style(i)
+			line, _ := plotter.NewLine(points)
+			line.LineStyle = style.LineStyle()
+			scatter, _ := plotter.NewScatter(points)
+			scatter.GlyphStyle = style.GlyphStyle()
+			yerr, _ := plotter.NewYErrorBars(points)
+			yerr.LineStyle = style.LineStyle()
+			yerr.LineStyle.Dashes = nil
+			p.Add(line, scatter, yerr)
+
+			etaLow, etaHigh := dcaGrid.XRange(i)
+			defaultLabel := fmt.Sprintf("%.3g < eta < %.3g", etaLow, etaHigh)
+			legend.Add(p, eicplot.Label(labels, i, defaultLabel), line, scatter)
+		}
+
+		if err := p.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	fig := eicplot.NewHeatmapFigure(dcaGrid, moreland.ExtendedBlackBody(), 0, *resLimit)
+	fig.Title = *title
+	fig.XLabel = "eta"
+	fig.YLabel = "p_T"
+	fig.ZLabel = zLabel
+	if err := fig.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// DCA returns the signed transverse and the longitudinal distances of
+// closest approach to vertex of a straight line through pos along p.  The
+// longitudinal distance is taken at the point of closest transverse
+// approach.  Neglecting the curvature is a good approximation when pos is
+// near vertex, as for the reference point of the first track segment.
+func DCA(pos, p, vertex kin.Vec3) (dxy, dz float64) {
+	d := pos.Sub(vertex)
+	pT := p.Pt()
+	dxy = (d.X*p.Y - d.Y*p.X) / pT
+	s := -(d.X*p.X + d.Y*p.Y) / (pT * pT)
+	dz = d.Z + s*p.Z
+	return dxy, dz
+}
+
+// ptCurve returns the resolution vs p_T in eta bin i, with the standard
+// errors of the estimates.  Cells with fewer than 3 tracks are left out.
+func ptCurve(g *eicplot.ResGrid, i int) plotutil.ErrorPoints {
+	var points plotutil.ErrorPoints
+	_, nBinsY := g.Dims()
+	for j := 0; j < nBinsY; j++ {
+		z := g.Z(i, j)
+		if math.IsNaN(z) {
+			continue
+		}
+		zErr := g.Err(i, j)
+		points.XYs = append(points.XYs, struct{ X, Y float64 }{g.Y(j), z})
+		points.YErrors = append(points.YErrors, struct{ Low, High float64 }{zErr, zErr})
+	}
+	return points
+}
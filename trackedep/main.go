@@ -17,7 +17,7 @@ import (
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
 
 options:
 `,
@@ -26,32 +26,81 @@ options:
 }
 
 var (
-	output = flag.String("output", "out.png", "output file")
+	output   = flag.String("output", "out.png", "output file")
+	nWorkers = eicplot.NewFlagWorkers()
+	histsOut = eicplot.NewFlagHistFile()
+	labels   = eicplot.NewFlagLabels()
+	legend   = eicplot.NewFlagLegend()
+	ref      = eicplot.NewFlagReference()
+
+	compare, drawCompare = eicplot.NewFlagCompare()
 )
 
 func main() {
 	flag.Usage = printUsage
 	flag.Parse()
-	if flag.NArg() != 1 {
+	if flag.NArg() < 1 {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
-
-	reader, err := proio.Open(flag.Arg(0))
-	if err != nil {
-		log.Fatal(err)
+	if *compare && (*ref < 0 || *ref >= flag.NArg()) {
+		log.Fatal("Reference is not an input file index")
 	}
-	defer reader.Close()
 
 	p, _ := plot.New()
 	p.X.Label.Text = "log_10{E dep. (MeV)}"
-	p.X.Tick.Marker = eicplot.PreciseTicks{5}
+	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
 	p.Y.Tick.Marker = eicplot.LogTicks{}
 	p.Y.Scale = eicplot.LogScale{}
 
-	hist := hbook.NewH1D(100, -9, 2)
+	histFile := &eicplot.HistFile{}
+	var hists []*hbook.H1D
+	for _, filename := range flag.Args() {
+		hist := makeEDepHist(filename, *nWorkers)
+		histFile.AddH1D(eicplot.HistName("edep_tracker", eicplot.FileTag(filename)), "log10 of tracker energy deposits in MeV", hist)
+		hists = append(hists, hist)
+	}
+
+	for i, hist := range hists {
+		hPlot := hplot.NewH1D(hist)
+		if len(hists) > 1 {
+			hPlot.FillColor = nil
+			hPlot.LineStyle = eicplot.Style(i).LineStyle()
+			hPlot.Infos.Style = hplot.HInfoNone
+		}
+		p.Add(hPlot)
+
+		label := eicplot.Label(labels, i, eicplot.FileTag(flag.Arg(i)))
+		if *compare && i != *ref {
+			c := eicplot.Compare(hist, hists[*ref])
+			fmt.Printf("%v vs %v: %v\n", label, eicplot.Label(labels, *ref, eicplot.FileTag(flag.Arg(*ref))), c)
+			if *drawCompare {
+				label += " (" + c.Summary() + ")"
+			}
+		}
+		if len(hists) > 1 {
+			legend.Add(p, label, hPlot)
+		}
+	}
+
+	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+func makeEDepHist(filename string, nWorkers int) *hbook.H1D {
+	workerHists := make([][]*hbook.H1D, nWorkers)
+	for i := range workerHists {
+		workerHists[i] = []*hbook.H1D{hbook.NewH1D(100, -9, 2)}
+	}
+
+	err := eicplot.ProcessEvents([]string{filename}, nWorkers, func(worker int, event *proio.Event) {
+		hist := workerHists[worker][0]
 
-	for event := range reader.ScanEvents() {
 		trackerIDs := event.TaggedEntries("Tracker")
 		for _, id := range trackerIDs {
 			eDep, ok := event.GetEntry(id).(*eic.EnergyDep)
@@ -61,10 +110,10 @@ func main() {
 
 			hist.Fill(math.Log10(float64(eDep.GetMean()*1000)), 1)
 		}
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	hPlot := hplot.NewH1D(hist)
-	p.Add(hPlot)
-
-	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	return eicplot.MergeH1DSets(workerHists)[0]
 }
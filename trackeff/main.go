@@ -3,24 +3,24 @@ package main
 import (
 	"flag"
 	"fmt"
-	"image/color"
 	"log"
 	"math"
 	"os"
 
 	"github.com/proio-org/go-proio"
 	"github.com/proio-org/go-proio-pb/model/eic"
-	"go-hep.org/x/hep/hbook"
 	"gonum.org/v1/plot"
-	"gonum.org/v1/plot/plotter"
+	"gonum.org/v1/plot/palette/moreland"
 	"gonum.org/v1/plot/plotutil"
 	"gonum.org/v1/plot/vg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
 
 options:
 `,
@@ -30,23 +30,56 @@ options:
 
 func main() {
 	pTMin := &eicplot.FloatArrayFlags{Array: []float64{0.5}}
-	pTMax := &eicplot.FloatArrayFlags{Array: []float64{100000}}
+	pTMax := &eicplot.FloatArrayFlags{Array: []float64{noPTMax}}
 	fracCut := &eicplot.FloatArrayFlags{Array: []float64{0.01}}
+	var interval eicplot.IntervalMethod
+	species := &SpeciesFlags{}
 	var (
 		etaLimit = flag.Float64("etalimit", 4, "maximum absolute value of eta")
 		nBins    = flag.Int("nbins", 80, "number of bins")
 		title    = flag.String("title", "", "plot title")
 		output   = flag.String("output", "out.png", "output file")
+		nWorkers = eicplot.NewFlagWorkers()
+		histsOut = eicplot.NewFlagHistFile()
+		matcher  = truth.NewFlagMatcher()
+		labels   = eicplot.NewFlagLabels()
+		legend   = eicplot.NewFlagLegend()
+		unique   = flag.Bool("unique", false, "count each generated particle at most once, ignoring clone tracks")
+		charged  = flag.Bool("charged", false, "count only charged generated particles")
+
+		perSpecies = flag.Bool("perspecies", false, "draw a curve for each -species instead of one for all")
+
+		drawMap  = flag.Bool("map", false, "draw a map in eta and p_T instead of curves, sized by -width and -height (implies -unique)")
+		mapErr   = flag.Bool("maperr", false, "draw the uncertainty on the efficiency in the map instead of the efficiency")
+		mapPTMin = flag.Float64("mapminpt", 0.5, "minimum transverse momentum of the map")
+		mapPTMax = flag.Float64("mapmaxpt", 30, "maximum transverse momentum of the map")
+		nBinsPT  = flag.Int("nbinspt", 10, "number of bins in transverse momentum of the map")
+		nBinsEta = flag.Int("nbinseta", 10, "number of bins in eta of the map")
+		minTotal = flag.Float64("mintotal", 10, "minimum number of generated particles in a map cell")
+		errLimit = flag.Float64("errlimit", 0.1, "maximum uncertainty in the color map with -maperr")
+
+		width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+		ratio = eicplot.NewFlagRatio()
+		ref   = eicplot.NewFlagReference()
 	)
 	flag.Var(pTMin, "minpt", "minimum transverse momentum")
 	flag.Var(pTMax, "maxpt", "maximum transverse momentum")
 	flag.Var(fracCut, "frac", "maximum fractional magnitude of the difference in momentum between track and true")
+	flag.Var(species, "species", "count only generated particles of this species (e, mu, pi, K, p or a PDG code; can be repeated)")
+	flag.Var(&interval, "interval", "efficiency interval method (clopper-pearson, wilson or bayesian)")
 	flag.Usage = printUsage
 	flag.Parse()
 	if flag.NArg() < 1 {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
+	if *ratio && (*ref < 0 || *ref >= flag.NArg()) {
+		log.Fatal("Reference is not an input file index")
+	}
+	if *perSpecies && len(species.Array) == 0 {
+		log.Fatal("-perspecies needs at least one -species")
+	}
 
 	p, _ := plot.New()
 	p.Title.Text = *title
@@ -59,51 +92,172 @@ func main() {
 	nSubs = intMax(nSubs, len(pTMax.Array))
 	nSubs = intMax(nSubs, len(fracCut.Array))
 
-	for i, filename := range flag.Args() {
-		for j := 0; j < nSubs; j++ {
-			iPTMin := intMin(j, len(pTMin.Array)-1)
-			iPTMax := intMin(j, len(pTMax.Array)-1)
-			iFracCut := intMin(j, len(fracCut.Array)-1)
-
-			plotters := makeTrackEffPlotters(filename, pTMin.Array[iPTMin], pTMax.Array[iPTMax], fracCut.Array[iFracCut], *etaLimit, *nBins)
-
-			pointColor := color.RGBA{A: 255}
-			switch i + j {
-			case 1:
-				pointColor = color.RGBA{G: 255, A: 255}
-			case 2:
-				pointColor = color.RGBA{B: 255, A: 255}
-			case 3:
-				pointColor = color.RGBA{R: 255, B: 127, G: 127, A: 255}
-			}
+	groups := []speciesGroup{species.Array}
+	if *perSpecies {
+		groups = nil
+		for _, s := range species.Array {
+			groups = append(groups, speciesGroup{s})
+		}
+	}
 
-			for _, p := range plotters {
-				switch t := p.(type) {
-				case *plotter.XErrorBars:
-					t.LineStyle.Color = pointColor
-				case *plotter.YErrorBars:
-					t.LineStyle.Color = pointColor
-				}
+	var cutSets []cutSet
+	for j := 0; j < nSubs; j++ {
+		iPTMin := intMin(j, len(pTMin.Array)-1)
+		iPTMax := intMin(j, len(pTMax.Array)-1)
+		iFracCut := intMin(j, len(fracCut.Array)-1)
+
+		for _, group := range groups {
+			cutSets = append(cutSets, cutSet{pTMin.Array[iPTMin], pTMax.Array[iPTMax], fracCut.Array[iFracCut], *charged, group})
+		}
+	}
+
+	if *drawMap && (flag.NArg() > 1 || len(cutSets) > 1) {
+		log.Fatal("-map needs a single input file and cut set")
+	}
+	// clone tracks would push cells above 1, off the color map
+	if *drawMap {
+		*unique = true
+	}
+	newGrid := func() *eicplot.EfficiencyGrid {
+		return eicplot.NewEfficiencyGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *mapPTMin, *mapPTMax)
+	}
+
+	histFile := &eicplot.HistFile{}
+	var mapGrid *eicplot.EfficiencyGrid
+	var series []plotutil.ErrorPoints
+	for _, filename := range flag.Args() {
+		effs, grids := makeTrackEffs(filename, cutSets, *etaLimit, *nBins, newGrid, matcher, *unique, *nWorkers)
+
+		for j, eff := range effs {
+			name := eicplot.HistName("eff", eicplot.FileTag(filename), cutSets[j].name())
+			histFile.AddH1D(name+"_pass", "matched tracks vs eta", eff.Pass)
+			histFile.AddH1D(name+"_total", "generated particles vs eta", eff.Total)
+
+			if *drawMap {
+				mapGrid = grids[j]
+				mapGrid.Interval = interval
+				mapGrid.MinTotal = *minTotal
+				histFile.AddH2D(eicplot.HistName(name, "map"), "efficiency vs eta and p_T", mapGrid.H2D())
+				histFile.AddH2D(eicplot.HistName(name, "map_err"), "uncertainty on efficiency vs eta and p_T", mapGrid.UncertaintyH2D())
+				histFile.AddH2D(eicplot.HistName(name, "map_pass"), "matched tracks vs eta and p_T", mapGrid.Pass)
+				histFile.AddH2D(eicplot.HistName(name, "map_total"), "generated particles vs eta and p_T", mapGrid.Total)
 			}
 
+			eff.Interval = interval
+			plotters, thumb := eff.Plotters(eicplot.Style(len(series)))
 			p.Add(plotters...)
+
+			label := cutSets[j].label()
+			if flag.NArg() > 1 {
+				label = eicplot.FileTag(filename) + ": " + label
+			}
+			legend.Add(p, eicplot.Label(labels, len(series), label), thumb)
+			series = append(series, eff.ErrorPoints())
+		}
+	}
+
+	if *drawMap {
+		var fig *eicplot.HeatmapFigure
+		if *mapErr {
+			fig = eicplot.NewHeatmapFigure(mapGrid.Uncertainties(), moreland.ExtendedBlackBody(), 0, *errLimit)
+			fig.ZLabel = "efficiency uncertainty"
+		} else {
+			fig = eicplot.NewHeatmapFigure(mapGrid, moreland.ExtendedBlackBody(), 0, 1)
+			fig.ZLabel = "efficiency"
+		}
+		fig.Title = *title
+		fig.XLabel = "eta"
+		fig.YLabel = "p_T"
+		if err := fig.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
 		}
+	} else if *ratio {
+		fig := eicplot.NewRatioFigure(p)
+		fig.Ratio.Y.Label.Text = "ratio to " + eicplot.FileTag(flag.Arg(*ref))
+		fig.AddFileRatios(series, len(cutSets), *ref)
+		if err := fig.Save(6*vg.Inch, 5*vg.Inch, *output); err != nil {
+			log.Fatal(err)
+		}
+	} else {
+		p.Save(6*vg.Inch, 4*vg.Inch, *output)
 	}
 
-	p.Save(6*vg.Inch, 4*vg.Inch, *output)
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
 }
 
-func makeTrackEffPlotters(filename string, pTMin, pTMax, fracCut, etaLimit float64, nBins int) []plot.Plotter {
-	etaHist := hbook.NewH1D(nBins, -etaLimit, etaLimit)
-	trueEtaHist := hbook.NewH1D(nBins, -etaLimit, etaLimit)
+// noPTMax is the default maximum transverse momentum, which is left out of
+// labels.
+const noPTMax = 100000
 
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+type cutSet struct {
+	pTMin, pTMax, fracCut float64
+	charged               bool
+	species               speciesGroup
+}
+
+func (c cutSet) name() string {
+	name := eicplot.HistName("ptmin"+eicplot.FormatCut(c.pTMin), "ptmax"+eicplot.FormatCut(c.pTMax), "frac"+eicplot.FormatCut(c.fracCut))
+	if c.charged {
+		name = eicplot.HistName(name, "charged")
+	}
+	if len(c.species) > 0 {
+		name = eicplot.HistName(name, c.species.name())
+	}
+	return name
+}
+
+func (c cutSet) label() string {
+	label := "pT>" + eicplot.FormatCut(c.pTMin)
+	if c.pTMax != noPTMax {
+		label += ", pT<" + eicplot.FormatCut(c.pTMax)
+	}
+	label += ", frac<" + eicplot.FormatCut(c.fracCut)
+	if c.charged {
+		label += ", charged"
+	}
+	if len(c.species) > 0 {
+		label += ", " + c.species.name()
+	}
+	return label
+}
+
+// selects reports whether generated particle part is counted.
+func (c cutSet) selects(part *eic.Particle, pT float64) bool {
+	if pT < c.pTMin || pT > c.pTMax {
+		return false
+	}
+	if c.charged && part.GetCharge() == 0 {
+		return false
+	}
+	return c.species.contains(part.GetPdg())
+}
+
+// makeTrackEffs returns, for each cut set, the efficiency vs eta and the
+// efficiency in a grid of eta and p_T from newGrid.
+func makeTrackEffs(filename string, cutSets []cutSet, etaLimit float64, nBins int, newGrid func() *eicplot.EfficiencyGrid, matcher *truth.Matcher, unique bool, nWorkers int) ([]*eicplot.Efficiency, []*eicplot.EfficiencyGrid) {
+	workerEffs := make([][]*eicplot.Efficiency, nWorkers)
+	workerGrids := make([][]*eicplot.EfficiencyGrid, nWorkers)
+	for i := range workerEffs {
+		for range cutSets {
+			workerEffs[i] = append(workerEffs[i], eicplot.NewEfficiency(nBins, -etaLimit, etaLimit))
+			workerGrids[i] = append(workerGrids[i], newGrid())
+		}
 	}
 
-	eventNum := 0
-	for event := range reader.ScanEvents() {
+	err := eicplot.ProcessEvents([]string{filename}, nWorkers, func(worker int, event *proio.Event) {
+		effs := workerEffs[worker]
+		grids := workerGrids[worker]
+
+		// particles already counted as passing, for each cut set
+		passed := make([]map[uint64]bool, len(cutSets))
+		for i := range passed {
+			passed[i] = make(map[uint64]bool)
+		}
+
 		ids := event.TaggedEntries("Reconstructed")
 		for _, id := range ids {
 			track, ok := event.GetEntry(id).(*eic.Track)
@@ -111,37 +265,9 @@ func makeTrackEffPlotters(filename string, pTMin, pTMax, fracCut, etaLimit float
 				continue
 			}
 
-			partCandID := make(map[uint64]uint64)
-			for _, obsID := range track.Observation {
-				eDep, ok := event.GetEntry(obsID).(*eic.EnergyDep)
-				if !ok {
-					continue
-				}
-
-				for _, sourceID := range eDep.Source {
-					simHit, ok := event.GetEntry(sourceID).(*eic.SimHit)
-					if ok {
-						partCandID[simHit.GetParticle()]++
-					}
-
-					_, ok = event.GetEntry(sourceID).(*eic.Particle)
-					if ok {
-						partCandID[sourceID]++
-					}
-				}
-			}
-
-			partID := uint64(0)
-			hitCount := uint64(0)
-			for id, count := range partCandID {
-				if count > hitCount {
-					partID = id
-					hitCount = count
-				}
-			}
-
-			part, ok := event.GetEntry(partID).(*eic.Particle)
-			if !ok {
+			match := matcher.Match(event, track)
+			part := match.Particle
+			if part == nil {
 				continue
 			}
 
@@ -149,25 +275,32 @@ func makeTrackEffPlotters(filename string, pTMin, pTMax, fracCut, etaLimit float
 				continue
 			}
 
-			pMag := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2) + math.Pow(float64(part.GetP().GetZ()), 2))
-			eta := math.Atanh(float64(part.GetP().GetZ()) / pMag)
-			pT := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2))
+			// a matched neutral particle has a NaN momentum difference, so it
+			// passes any -frac cut unless excluded by -charged
 			chargeMag := math.Abs(float64(part.GetCharge()))
-			poqMag := pMag / chargeMag
-			diffMag := math.Sqrt(math.Pow(track.Segment[0].GetPoq().GetX()-float64(part.GetP().GetX())/chargeMag, 2) +
-				math.Pow(track.Segment[0].GetPoq().GetY()-float64(part.GetP().GetY())/chargeMag, 2) +
-				math.Pow(track.Segment[0].GetPoq().GetZ()-float64(part.GetP().GetZ())/chargeMag, 2))
-			fracDiff := diffMag / poqMag
-
-			// cuts
-			if pT < pTMin || pT > pTMax {
-				continue
-			}
-			if fracDiff > fracCut {
-				continue
-			}
 
-			etaHist.Fill(eta, 1)
+			partP := kin.Vec3FromXYZF(part.GetP())
+			eta := partP.Eta()
+			pT := partP.Pt()
+			poq := partP.Scale(1 / chargeMag)
+			diffMag := kin.Vec3FromXYZD(track.Segment[0].GetPoq()).Sub(poq).Mag()
+			fracDiff := diffMag / poq.Mag()
+
+			for i, cuts := range cutSets {
+				if !cuts.selects(part, pT) {
+					continue
+				}
+				if fracDiff > cuts.fracCut {
+					continue
+				}
+				if unique && passed[i][match.ParticleID] {
+					continue
+				}
+				passed[i][match.ParticleID] = true
+
+				effs[i].Pass.Fill(eta, 1)
+				grids[i].Pass.Fill(eta, pT, 1)
+			}
 		}
 
 		ids = event.TaggedEntries("GenStable")
@@ -177,47 +310,32 @@ func makeTrackEffPlotters(filename string, pTMin, pTMax, fracCut, etaLimit float
 				continue
 			}
 
-			pMag := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2) + math.Pow(float64(part.GetP().GetZ()), 2))
-			eta := math.Atanh(float64(part.GetP().GetZ()) / pMag)
-			pT := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2))
+			partP := kin.Vec3FromXYZF(part.GetP())
+			eta := partP.Eta()
+			pT := partP.Pt()
 
-			// cuts
-			if pT < pTMin || pT > pTMax {
-				continue
-			}
+			for i, cuts := range cutSets {
+				if !cuts.selects(part, pT) {
+					continue
+				}
 
-			trueEtaHist.Fill(eta, 1)
+				effs[i].Total.Fill(eta, 1)
+				grids[i].Total.Fill(eta, pT, 1)
+			}
 		}
-
-		eventNum++
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	reader.Close()
-
-	points := make(plotter.XYs, nBins)
-	xErrors := make(plotter.XErrors, nBins)
-	yErrors := make(plotter.YErrors, nBins)
-	binHalfWidth := etaLimit / float64(nBins)
-	binSigma := binHalfWidth / math.Sqrt(3.)
-	for i := range points {
-		trueX, trueY := trueEtaHist.XY(i)
-
-		points[i].X = trueX + binHalfWidth
-		xErrors[i].Low = binSigma
-		xErrors[i].High = binSigma
-
-		_, trackY := etaHist.XY(i)
-		if trueY > 0 {
-			points[i].Y = trackY / trueY
-			yErrors[i].Low = math.Sqrt((1 - trackY/trueY) * trackY / math.Pow(trueY, 2))
-			yErrors[i].High = yErrors[i].Low
+	effs, grids := workerEffs[0], workerGrids[0]
+	for w, others := range workerEffs[1:] {
+		for i, other := range others {
+			effs[i].Merge(other)
+			grids[i].Merge(workerGrids[w+1][i])
 		}
 	}
-	errPoints := plotutil.ErrorPoints{points, xErrors, yErrors}
-	xerr, _ := plotter.NewXErrorBars(errPoints)
-	yerr, _ := plotter.NewYErrorBars(errPoints)
-
-	return []plot.Plotter{xerr, yerr}
+	return effs, grids
 }
 
 func intMin(a, b int) int {
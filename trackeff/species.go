@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Species selects generated particles by the magnitude of their PDG code, so
+// that particles and antiparticles are selected together.
+type Species struct {
+	Name string
+	PDG  int32
+}
+
+var knownSpecies = []Species{
+	{"e", 11},
+	{"mu", 13},
+	{"pi", 211},
+	{"K", 321},
+	{"p", 2212},
+}
+
+// SpeciesFlags accumulates species given by name or PDG code.
+type SpeciesFlags struct {
+	Array []Species
+}
+
+func (f *SpeciesFlags) Set(valueStr string) error {
+	for _, species := range knownSpecies {
+		if species.Name == valueStr {
+			f.Array = append(f.Array, species)
+			return nil
+		}
+	}
+
+	pdg, err := strconv.ParseInt(valueStr, 10, 32)
+	if err != nil {
+		return fmt.Errorf("unknown species %q", valueStr)
+	}
+	if pdg < 0 {
+		pdg = -pdg
+	}
+	for _, species := range knownSpecies {
+		if species.PDG == int32(pdg) {
+			f.Array = append(f.Array, species)
+			return nil
+		}
+	}
+	f.Array = append(f.Array, Species{strconv.FormatInt(pdg, 10), int32(pdg)})
+	return nil
+}
+
+func (f *SpeciesFlags) String() string {
+	var names []string
+	for _, species := range f.Array {
+		names = append(names, species.Name)
+	}
+	return strings.Join(names, ",")
+}
+
+// speciesGroup is the set of species filling one efficiency curve.  An
+// empty group accepts any particle.
+type speciesGroup []Species
+
+func (g speciesGroup) contains(pdg int32) bool {
+	if len(g) == 0 {
+		return true
+	}
+	if pdg < 0 {
+		pdg = -pdg
+	}
+	for _, species := range g {
+		if species.PDG == pdg {
+			return true
+		}
+	}
+	return false
+}
+
+func (g speciesGroup) name() string {
+	var names []string
+	for _, species := range g {
+		names = append(names, species.Name)
+	}
+	return strings.Join(names, "+")
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+	"gonum.org/v1/plot"
+	"gonum.org/v1/plot/palette/moreland"
+
+	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
+)
+
+var (
+	pTMin     = flag.Float64("minpt", 0.5, "minimum reconstructed transverse momentum")
+	pTMax     = flag.Float64("maxpt", 30, "maximum reconstructed transverse momentum")
+	etaLimit  = flag.Float64("etalimit", 4, "maximum absolute value of reconstructed eta")
+	rateLimit = flag.Float64("ratelimit", 0.1, "maximum fake rate in the color map")
+	nBinsPT   = flag.Int("nbinspt", 10, "number of bins in transverse momentum")
+	nBinsEta  = flag.Int("nbinseta", 20, "number of bins in eta")
+	vsPT      = flag.Bool("vspt", false, "plot the fake rate vs p_T instead of eta")
+	drawMap   = flag.Bool("map", false, "draw a map in eta and p_T instead of curves")
+	title     = flag.String("title", "", "plot title")
+	output    = flag.String("output", "out.png", "output file")
+	nWorkers  = eicplot.NewFlagWorkers()
+	histsOut  = eicplot.NewFlagHistFile()
+	matcher   = truth.NewFlagMatcher()
+	labels    = eicplot.NewFlagLabels()
+	legend    = eicplot.NewFlagLegend()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+	hitEdges = &eicplot.FloatArrayFlags{}
+	interval eicplot.IntervalMethod
+)
+
+func printUsage() {
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
+
+A track is fake if it is not matched to a particle under the -match policy.
+Under the default majority policy, only tracks without energy deposits from
+any particle are fake; use -match purity to require a fraction of the hits
+to come from one particle.
+
+options:
+`,
+	)
+	flag.PrintDefaults()
+}
+
+func main() {
+	flag.Var(hitEdges, "hitsplit", "split the curves at this number of track hits (can be repeated)")
+	flag.Var(&interval, "interval", "binomial interval method (clopper-pearson, wilson or bayesian)")
+	flag.Usage = printUsage
+	flag.Parse()
+	if flag.NArg() < 1 {
+		printUsage()
+		log.Fatal("Invalid arguments")
+	}
+	sort.Float64s(hitEdges.Array)
+	classes := hitClasses(hitEdges.Array)
+
+	workerGrids := make([][]*eicplot.EfficiencyGrid, *nWorkers)
+	for i := range workerGrids {
+		for range classes {
+			workerGrids[i] = append(workerGrids[i], eicplot.NewEfficiencyGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax))
+		}
+	}
+
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		grids := workerGrids[worker]
+
+		ids := event.TaggedEntries("Reconstructed")
+		for _, id := range ids {
+			track, ok := event.GetEntry(id).(*eic.Track)
+			if !ok || len(track.Segment) == 0 {
+				continue
+			}
+
+			match := matcher.Match(event, track)
+			fake := !match.Matched()
+
+			poq := kin.Vec3FromXYZD(track.Segment[0].GetPoq())
+			nHits := float64(len(track.Observation))
+			for i, class := range classes {
+				if class.contains(nHits) {
+					grids[i].Fill(poq.Eta(), poq.Pt(), fake)
+				}
+			}
+		}
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	grids := workerGrids[0]
+	for _, other := range workerGrids[1:] {
+		for i, g := range grids {
+			g.Merge(other[i])
+		}
+	}
+
+	histFile := &eicplot.HistFile{}
+	for i, g := range grids {
+		etaEff, ptEff := g.ProjectX(), g.ProjectY()
+		var nFake, nTotal float64
+		for j := 0; j < etaEff.Len(); j++ {
+			nFake += etaEff.Pass.Value(j)
+			nTotal += etaEff.Total.Value(j)
+		}
+		fmt.Printf("%v: %v of %v tracks fake\n", classes[i].label(), nFake, nTotal)
+
+		name := eicplot.HistName("fake", classes[i].name())
+		histFile.AddH2D(name, "fake rate vs eta and p_T", g.H2D())
+		histFile.AddH1D(eicplot.HistName(name, "eta_pass"), "fake tracks vs eta", etaEff.Pass)
+		histFile.AddH1D(eicplot.HistName(name, "eta_total"), "reconstructed tracks vs eta", etaEff.Total)
+		histFile.AddH1D(eicplot.HistName(name, "pt_pass"), "fake tracks vs p_T", ptEff.Pass)
+		histFile.AddH1D(eicplot.HistName(name, "pt_total"), "reconstructed tracks vs p_T", ptEff.Total)
+	}
+	if *histsOut != "" {
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	if *drawMap {
+		if len(classes) > 1 {
+			log.Fatal("-map does not support -hitsplit")
+		}
+		fig := eicplot.NewHeatmapFigure(grids[0], moreland.ExtendedBlackBody(), 0, *rateLimit)
+		fig.Title = *title
+		fig.XLabel = "eta"
+		fig.YLabel = "p_T"
+		fig.ZLabel = "fake rate"
+		if err := fig.Save(width.Length, height.Length, *output); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	p, _ := plot.New()
+	p.Title.Text = *title
+	p.X.Label.Text = "eta"
+	if *vsPT {
+		p.X.Label.Text = "p_T"
+	}
+	p.Y.Label.Text = "fake rate"
+	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
+
+	for i, g := range grids {
+		eff := g.ProjectX()
+		if *vsPT {
+			eff = g.ProjectY()
+		}
+		eff.Interval = interval
+		plotters, thumb := eff.Plotters(eicplot.Style(i))
+		p.Add(plotters...)
+		if len(classes) > 1 {
+			legend.Add(p, eicplot.Label(labels, i, classes[i].label()), thumb)
+		}
+	}
+
+	if err := p.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// hitClass selects tracks with a number of hits in [min, max).
+type hitClass struct {
+	min, max float64
+}
+
+func hitClasses(edges []float64) []hitClass {
+	classes := []hitClass{{0, math.Inf(1)}}
+	for _, edge := range edges {
+		last := len(classes) - 1
+		classes = append(classes, hitClass{edge, classes[last].max})
+		classes[last].max = edge
+	}
+	return classes
+}
+
+func (c hitClass) contains(nHits float64) bool {
+	return nHits >= c.min && nHits < c.max
+}
+
+func (c hitClass) name() string {
+	if c.min == 0 && math.IsInf(c.max, 1) {
+		return "all"
+	}
+	name := "nhitsmin" + eicplot.FormatCut(c.min)
+	if !math.IsInf(c.max, 1) {
+		name += "_nhitsmax" + eicplot.FormatCut(c.max)
+	}
+	return name
+}
+
+func (c hitClass) label() string {
+	switch {
+	case c.min == 0 && math.IsInf(c.max, 1):
+		return "all tracks"
+	case c.min == 0:
+		return "hits<" + eicplot.FormatCut(c.max)
+	case math.IsInf(c.max, 1):
+		return "hits>=" + eicplot.FormatCut(c.min)
+	}
+	return eicplot.FormatCut(c.min) + "<=hits<" + eicplot.FormatCut(c.max)
+}
@@ -10,13 +10,11 @@ import (
 	"github.com/proio-org/go-proio"
 	"github.com/proio-org/go-proio-pb/model/eic"
 	"go-hep.org/x/hep/hbook"
-	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/palette/moreland"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg/draw"
-	"gonum.org/v1/plot/vg/vgimg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
 )
 
 var (
@@ -28,10 +26,15 @@ var (
 	nBinsEta  = flag.Int("nbinseta", 10, "number of bins in eta")
 	title     = flag.String("title", "", "plot title")
 	output    = flag.String("output", "out.png", "output file")
+	nWorkers  = eicplot.NewFlagWorkers()
+	histsOut  = eicplot.NewFlagHistFile()
+	matcher   = truth.NewFlagMatcher()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
 
 options:
 `,
@@ -42,27 +45,19 @@ options:
 func main() {
 	flag.Usage = printUsage
 	flag.Parse()
-	if flag.NArg() != 1 {
+	if flag.NArg() < 1 {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
 
-	p, _ := plot.New()
-	p.Title.Text = *title
-	p.X.Label.Text = "eta"
-	p.Y.Label.Text = "p_T"
-	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
-	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
-
-	resGrid := NewPullGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
-
-	filename := flag.Arg(0)
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+	resGrids := make([]*PullGrid, *nWorkers)
+	for i := range resGrids {
+		resGrids[i] = NewPullGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
 	}
 
-	for event := range reader.ScanEvents() {
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		resGrid := resGrids[worker]
+
 		ids := event.TaggedEntries("Reconstructed")
 		for _, id := range ids {
 			track, ok := event.GetEntry(id).(*eic.Track)
@@ -70,34 +65,8 @@ func main() {
 				continue
 			}
 
-			partCandID := make(map[uint64]uint64)
-			for _, obsID := range track.Observation {
-				eDep, ok := event.GetEntry(obsID).(*eic.EnergyDep)
-				if !ok {
-					continue
-				}
-
-				for _, sourceID := range eDep.Source {
-					simHit, ok := event.GetEntry(sourceID).(*eic.SimHit)
-					if !ok {
-						continue
-					}
-
-					partCandID[simHit.GetParticle()]++
-				}
-			}
-
-			partID := uint64(0)
-			hitCount := uint64(0)
-			for id, count := range partCandID {
-				if count > hitCount {
-					partID = id
-					hitCount = count
-				}
-			}
-
-			part, ok := event.GetEntry(partID).(*eic.Particle)
-			if !ok {
+			part := matcher.Match(event, track).Particle
+			if part == nil {
 				continue
 			}
 
@@ -105,54 +74,42 @@ func main() {
 				continue
 			}
 
-			pMag := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2) + math.Pow(float64(part.GetP().GetZ()), 2))
-			eta := math.Atanh(float64(part.GetP().GetZ()) / pMag)
-			pT := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2))
+			partP := kin.Vec3FromXYZF(part.GetP())
+			eta := partP.Eta()
+			pT := partP.Pt()
 			chargeMag := math.Abs(float64(part.GetCharge()))
-			poqMag := pMag / chargeMag
-			trackPoqMag := math.Sqrt(math.Pow(track.Segment[0].GetPoq().GetX(), 2) +
-				math.Pow(track.Segment[0].GetPoq().GetY(), 2) +
-				math.Pow(track.Segment[0].GetPoq().GetZ(), 2))
+			poqMag := partP.Mag() / chargeMag
+			trackPoqMag := kin.Vec3FromXYZD(track.Segment[0].GetPoq()).Mag()
 
 			resGrid.Fill(eta, pT, trackPoqMag/poqMag)
 		}
+	})
+	if err != nil {
+		log.Fatal(err)
 	}
 
-	reader.Close()
-
-	img := vgimg.New(670, 400)
-	dc := draw.New(img)
-	dc0 := draw.Crop(dc, 0, -70, 0, 0)
-	dc1 := draw.Crop(dc, 620, 0, 0, 0)
-
-	colorMap := moreland.SmoothBlueRed()
-	colorMap.SetMin(1.0 - *pullLimit)
-	colorMap.SetMax(1.0 + *pullLimit)
-	heatMap := plotter.NewHeatMap(resGrid, colorMap.Palette(1000))
-	heatMap.Min = 1.0 - *pullLimit
-	heatMap.Max = 1.0 + *pullLimit
-	p.Add(heatMap)
-
-	p.Draw(dc0)
-
-	p, _ = plot.New()
-
-	colorBar := &plotter.ColorBar{ColorMap: colorMap}
-	colorBar.Vertical = true
-	p.Add(colorBar)
-	p.HideX()
-	p.Y.Padding = 0
-	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 3}
-
-	p.Draw(dc1)
+	resGrid := resGrids[0]
+	for _, g := range resGrids[1:] {
+		resGrid.Merge(g)
+	}
 
-	w, err := os.Create(*output)
-	if err != nil {
-		log.Panic(err)
+	if *histsOut != "" {
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(eicplot.HistName("pull"), "mean momentum pull vs eta and p_T", resGrid.H2D())
+		histFile.AddH2D(eicplot.HistName("pull", "counts"), "matched tracks vs eta and p_T", resGrid.hCount)
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
 	}
-	png := vgimg.PngCanvas{Canvas: img}
-	if _, err = png.WriteTo(w); err != nil {
-		log.Panic(err)
+
+	fig := eicplot.NewHeatmapFigure(resGrid, moreland.SmoothBlueRed(), 1.0-*pullLimit, 1.0+*pullLimit)
+	fig.Title = *title
+	fig.XLabel = "eta"
+	fig.YLabel = "p_T"
+	fig.ZLabel = "mean reconstructed / true |p/q|"
+	fig.ZTicks = eicplot.PreciseTicks{NSuggestedTicks: 3}
+	if err := fig.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
 	}
 }
 
@@ -180,6 +137,27 @@ func (g *PullGrid) Fill(x, y, z float64) {
 	g.hV2.Fill(x, y, z*z)
 }
 
+// Merge adds the sums filled into other, which must have identical binning.
+func (g *PullGrid) Merge(other *PullGrid) {
+	eicplot.MergeH2D(g.hCount, other.hCount)
+	eicplot.MergeH2D(g.hV, other.hV)
+	eicplot.MergeH2D(g.hV2, other.hV2)
+}
+
+// H2D returns a histogram with the content of each cell set to Z, leaving
+// out cells with too few entries.
+func (g *PullGrid) H2D() *hbook.H2D {
+	h := hbook.NewH2D(g.nBinsX, g.xLow, g.xHigh, g.nBinsY, g.yLow, g.yHigh)
+	for i := 0; i < g.nBinsX; i++ {
+		for j := 0; j < g.nBinsY; j++ {
+			if z := g.Z(i, j); !math.IsNaN(z) {
+				h.Fill(g.X(i), g.Y(j), z)
+			}
+		}
+	}
+	return h
+}
+
 func (g *PullGrid) Dims() (int, int) {
 	return g.nBinsX, g.nBinsY
 }
@@ -187,7 +165,7 @@ func (g *PullGrid) Dims() (int, int) {
 func (g *PullGrid) Z(i, j int) float64 {
 	n := g.hCount.GridXYZ().Z(i, j)
 	if n < 3 {
-		return 0
+		return math.NaN()
 	}
 	mean := g.hV.GridXYZ().Z(i, j) / n
 
@@ -9,29 +9,35 @@ import (
 
 	"github.com/proio-org/go-proio"
 	"github.com/proio-org/go-proio-pb/model/eic"
-	"go-hep.org/x/hep/hbook"
-	"gonum.org/v1/plot"
 	"gonum.org/v1/plot/palette/moreland"
-	"gonum.org/v1/plot/plotter"
-	"gonum.org/v1/plot/vg/draw"
-	"gonum.org/v1/plot/vg/vgimg"
 
 	"github.com/decibelcooper/eicplot"
+	"github.com/decibelcooper/eicplot/kin"
+	"github.com/decibelcooper/eicplot/truth"
 )
 
 var (
 	pTMin    = flag.Float64("minpt", 0.5, "minimum transverse momentum")
 	pTMax    = flag.Float64("maxpt", 30, "maximum transverse momentum")
 	etaLimit = flag.Float64("etalimit", 4, "maximum absolute value of eta")
-	resLimit = flag.Float64("reslimit", 0.1, "maximum momentum resolution in the color map")
+	resLimit = flag.Float64("reslimit", 0, "maximum resolution in the color map, or 0 for the observable default (p 0.1, theta and phi 5 mrad, eta 0.01)")
 	nBinsPT  = flag.Int("nbinspt", 10, "number of bins in transverse momentum")
 	nBinsEta = flag.Int("nbinseta", 10, "number of bins in eta")
 	title    = flag.String("title", "", "plot title")
 	output   = flag.String("output", "out.png", "output file")
+	nSigma   = flag.Float64("nsigma", 2.5, "number of standard deviations kept by the truncated and gauss estimators")
+	nWorkers = eicplot.NewFlagWorkers()
+	histsOut = eicplot.NewFlagHistFile()
+	matcher  = truth.NewFlagMatcher()
+
+	width, height = eicplot.NewFlagCanvasSize(670, 400)
+
+	estimator  eicplot.Estimator
+	observable Observable
 )
 
 func printUsage() {
-	fmt.Fprintf(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-file>
+	fmt.Fprint(os.Stderr, `Usage: `+os.Args[0]+` [options] <proio-input-files>...
 
 options:
 `,
@@ -40,29 +46,32 @@ options:
 }
 
 func main() {
+	flag.Var(&estimator, "estimator", "resolution estimator (rms, truncated, gauss or central68)")
+	flag.Var(&observable, "observable", "track quantity to resolve (p, theta, phi or eta)")
 	flag.Usage = printUsage
 	flag.Parse()
-	if flag.NArg() != 1 {
+	if flag.NArg() < 1 {
 		printUsage()
 		log.Fatal("Invalid arguments")
 	}
+	zMax := *resLimit
+	if zMax == 0 {
+		zMax = observableResLimits[observable]
+	}
 
-	p, _ := plot.New()
-	p.Title.Text = *title
-	p.X.Label.Text = "eta"
-	p.Y.Label.Text = "p_T"
-	p.X.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
-	p.Y.Tick.Marker = eicplot.PreciseTicks{NSuggestedTicks: 5}
-
-	resGrid := NewResGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
-
-	filename := flag.Arg(0)
-	reader, err := proio.Open(filename)
-	if err != nil {
-		log.Fatal(err)
+	resGrids := make([]*eicplot.ResGrid, *nWorkers)
+	for i := range resGrids {
+		resGrids[i] = eicplot.NewResGrid(*nBinsEta, -*etaLimit, *etaLimit, *nBinsPT, *pTMin, *pTMax)
+		resGrids[i].Estimator = estimator
+		resGrids[i].NSigma = *nSigma
+		if observable != Momentum {
+			resGrids[i].Empty = math.NaN()
+		}
 	}
 
-	for event := range reader.ScanEvents() {
+	err := eicplot.ProcessEvents(flag.Args(), *nWorkers, func(worker int, event *proio.Event) {
+		resGrid := resGrids[worker]
+
 		ids := event.TaggedEntries("Reconstructed")
 		for _, id := range ids {
 			track, ok := event.GetEntry(id).(*eic.Track)
@@ -70,34 +79,8 @@ func main() {
 				continue
 			}
 
-			partCandID := make(map[uint64]uint64)
-			for _, obsID := range track.Observation {
-				eDep, ok := event.GetEntry(obsID).(*eic.EnergyDep)
-				if !ok {
-					continue
-				}
-
-				for _, sourceID := range eDep.Source {
-					simHit, ok := event.GetEntry(sourceID).(*eic.SimHit)
-					if !ok {
-						continue
-					}
-
-					partCandID[simHit.GetParticle()]++
-				}
-			}
-
-			partID := uint64(0)
-			hitCount := uint64(0)
-			for id, count := range partCandID {
-				if count > hitCount {
-					partID = id
-					hitCount = count
-				}
-			}
-
-			part, ok := event.GetEntry(partID).(*eic.Particle)
-			if !ok {
+			part := matcher.Match(event, track).Particle
+			if part == nil {
 				continue
 			}
 
@@ -105,101 +88,48 @@ func main() {
 				continue
 			}
 
-			pMag := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2) + math.Pow(float64(part.GetP().GetZ()), 2))
-			eta := math.Atanh(float64(part.GetP().GetZ()) / pMag)
-			pT := math.Sqrt(math.Pow(float64(part.GetP().GetX()), 2) + math.Pow(float64(part.GetP().GetY()), 2))
+			// a neutral particle has no p/q to compare with
 			chargeMag := math.Abs(float64(part.GetCharge()))
-			poqMag := pMag / chargeMag
-			trackPoqMag := math.Sqrt(math.Pow(track.Segment[0].GetPoq().GetX(), 2) +
-				math.Pow(track.Segment[0].GetPoq().GetY(), 2) +
-				math.Pow(track.Segment[0].GetPoq().GetZ(), 2))
-
-			resGrid.Fill(eta, pT, trackPoqMag/poqMag)
-		}
-	}
-
-	reader.Close()
-
-	img := vgimg.New(670, 400)
-	dc := draw.New(img)
-	dc0 := draw.Crop(dc, 0, -70, 0, 0)
-	dc1 := draw.Crop(dc, 620, 0, 0, 0)
-
-	colorMap := moreland.ExtendedBlackBody()
-	colorMap.SetMin(0)
-	colorMap.SetMax(*resLimit)
-	pal := colorMap.Palette(1000)
-	heatMap := plotter.NewHeatMap(resGrid, pal)
-	heatMap.Min = 0
-	heatMap.Max = *resLimit
-	p.Add(heatMap)
-
-	p.Draw(dc0)
-
-	p, _ = plot.New()
-
-	colorBar := &plotter.ColorBar{ColorMap: colorMap}
-	colorBar.Vertical = true
-	p.Add(colorBar)
-	p.HideX()
-	p.Y.Padding = 0
+			if chargeMag == 0 {
+				continue
+			}
 
-	p.Draw(dc1)
+			partP := kin.Vec3FromXYZF(part.GetP())
+			eta := partP.Eta()
+			pT := partP.Pt()
+			trackPoq := kin.Vec3FromXYZD(track.Segment[0].GetPoq())
 
-	w, err := os.Create(*output)
+			resGrid.Fill(eta, pT, observable.Value(trackPoq, partP, chargeMag))
+		}
+	})
 	if err != nil {
-		log.Panic(err)
-	}
-	png := vgimg.PngCanvas{Canvas: img}
-	if _, err = png.WriteTo(w); err != nil {
-		log.Panic(err)
+		log.Fatal(err)
 	}
-}
-
-type ResGrid struct {
-	hCount, hV, hV2 *hbook.H2D
-	nBinsX, nBinsY  int
-	xLow, xHigh     float64
-	yLow, yHigh     float64
-}
 
-func NewResGrid(nBinsX int, xLow, xHigh float64, nBinsY int, yLow, yHigh float64) *ResGrid {
-	return &ResGrid{
-		hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
-		hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
-		hbook.NewH2D(nBinsX, xLow, xHigh, nBinsY, yLow, yHigh),
-		nBinsX, nBinsY,
-		xLow, xHigh,
-		yLow, yHigh,
+	resGrid := resGrids[0]
+	for _, g := range resGrids[1:] {
+		resGrid.Merge(g)
 	}
-}
-
-func (g *ResGrid) Fill(x, y, z float64) {
-	g.hCount.Fill(x, y, 1)
-	g.hV.Fill(x, y, z)
-	g.hV2.Fill(x, y, z*z)
-}
-
-func (g *ResGrid) Dims() (int, int) {
-	return g.nBinsX, g.nBinsY
-}
 
-func (g *ResGrid) Z(i, j int) float64 {
-	n := g.hCount.GridXYZ().Z(i, j)
-	if n < 3 {
-		return 1
+	if *histsOut != "" {
+		name := eicplot.HistName("res", estimator.String())
+		if observable != Momentum {
+			name = eicplot.HistName("res", observable.String(), estimator.String())
+		}
+		histFile := &eicplot.HistFile{}
+		histFile.AddH2D(name, observable.Label()+" vs eta and p_T", resGrid.H2D())
+		histFile.AddH2D(eicplot.HistName(name, "counts"), "matched tracks vs eta and p_T", resGrid.Counts())
+		if err := histFile.Write(*histsOut); err != nil {
+			log.Fatal(err)
+		}
 	}
-	mean := g.hV.GridXYZ().Z(i, j) / n
-	mean2 := g.hV2.GridXYZ().Z(i, j) / n
 
-	stddev := math.Sqrt(mean2 - mean*mean)
-	return stddev
-}
-
-func (g *ResGrid) X(i int) float64 {
-	return g.hCount.GridXYZ().X(i)
-}
-
-func (g *ResGrid) Y(j int) float64 {
-	return g.hCount.GridXYZ().Y(j)
+	fig := eicplot.NewHeatmapFigure(resGrid, moreland.ExtendedBlackBody(), 0, zMax)
+	fig.Title = *title
+	fig.XLabel = "eta"
+	fig.YLabel = "p_T"
+	fig.ZLabel = observable.Label(estimator.String())
+	if err := fig.Save(width.Length, height.Length, *output); err != nil {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/decibelcooper/eicplot/kin"
+)
+
+// Observable selects the track quantity whose resolution is mapped.
+type Observable int
+
+const (
+	// Momentum is the ratio of reconstructed to true |p/q|.
+	Momentum Observable = iota
+	// Theta, Phi and Eta are differences between the directions of the
+	// reconstructed and true momenta, with the angles in mrad.
+	Theta
+	Phi
+	Eta
+)
+
+var observableNames = map[Observable]string{
+	Momentum: "p",
+	Theta:    "theta",
+	Phi:      "phi",
+	Eta:      "eta",
+}
+
+var observableLabels = map[Observable]string{
+	Momentum: "momentum resolution",
+	Theta:    "theta resolution",
+	Phi:      "phi resolution",
+	Eta:      "eta resolution",
+}
+
+var observableUnits = map[Observable]string{
+	Theta: "mrad",
+	Phi:   "mrad",
+}
+
+// observableResLimits are the default maxima of the color map.
+var observableResLimits = map[Observable]float64{
+	Momentum: 0.1,
+	Theta:    5,
+	Phi:      5,
+	Eta:      0.01,
+}
+
+func (o *Observable) Set(valueStr string) error {
+	for observable, name := range observableNames {
+		if name == valueStr {
+			*o = observable
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown observable %q", valueStr)
+}
+
+func (o *Observable) String() string {
+	return observableNames[*o]
+}
+
+// Label describes the resolution, with the unit and the given qualifiers in
+// parentheses.
+func (o Observable) Label(qualifiers ...string) string {
+	if unit := observableUnits[o]; unit != "" {
+		qualifiers = append([]string{unit}, qualifiers...)
+	}
+	if len(qualifiers) == 0 {
+		return observableLabels[o]
+	}
+	return observableLabels[o] + " (" + strings.Join(qualifiers, ", ") + ")"
+}
+
+// Value compares the reconstructed track momentum over charge to the
+// momentum partP of the true particle with charge magnitude chargeMag, which
+// must not be 0.  The directions are compared without the charge.
+func (o Observable) Value(trackPoq, partP kin.Vec3, chargeMag float64) float64 {
+	switch o {
+	case Theta:
+		return 1000 * (trackPoq.Theta() - partP.Theta())
+	case Phi:
+		return 1000 * math.Remainder(trackPoq.Phi()-partP.Phi(), 2*math.Pi)
+	case Eta:
+		return trackPoq.Eta() - partP.Eta()
+	default:
+		return trackPoq.Mag() / (partP.Mag() / chargeMag)
+	}
+}
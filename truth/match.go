@@ -0,0 +1,153 @@
+// Package truth associates reconstructed tracks with the generated particles
+// that produced their hits.
+package truth
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+)
+
+// Policy selects which candidate particles are accepted as a track's match.
+type Policy int
+
+const (
+	// Majority accepts the particle contributing the most hits.
+	Majority Policy = iota
+	// MinPurity additionally requires the shared hit fraction to reach
+	// Matcher.MinPurity.
+	MinPurity
+	// MinShared additionally requires at least Matcher.MinShared shared hits.
+	MinShared
+)
+
+var policyNames = map[Policy]string{
+	Majority:  "majority",
+	MinPurity: "purity",
+	MinShared: "shared",
+}
+
+func (p *Policy) Set(valueStr string) error {
+	for policy, name := range policyNames {
+		if name == valueStr {
+			*p = policy
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown matching policy %q", valueStr)
+}
+
+func (p *Policy) String() string {
+	return policyNames[*p]
+}
+
+// Matcher performs majority-vote truth matching of tracks.  Each source of
+// each observed energy deposit is one vote, whether it refers to a SimHit
+// (voting for the SimHit's particle) or directly to a Particle.
+type Matcher struct {
+	Policy    Policy
+	MinPurity float64
+	MinShared int
+}
+
+type Contributor struct {
+	ID    uint64
+	NHits int
+}
+
+type Match struct {
+	// Particle is nil if the track is not matched under the policy.
+	Particle   *eic.Particle
+	ParticleID uint64
+	NShared    int
+	NHits      int
+	Purity     float64
+	// Contributors is sorted by decreasing hit count.
+	Contributors []Contributor
+}
+
+func (m *Match) Matched() bool {
+	return m.Particle != nil
+}
+
+func (m *Matcher) Match(event *proio.Event, track *eic.Track) *Match {
+	partCandID := make(map[uint64]int)
+	nHits := 0
+	for _, obsID := range track.Observation {
+		eDep, ok := event.GetEntry(obsID).(*eic.EnergyDep)
+		if !ok {
+			continue
+		}
+
+		for _, sourceID := range eDep.Source {
+			switch source := event.GetEntry(sourceID).(type) {
+			case *eic.SimHit:
+				partCandID[source.GetParticle()]++
+				nHits++
+			case *eic.Particle:
+				partCandID[sourceID]++
+				nHits++
+			}
+		}
+	}
+
+	match := &Match{NHits: nHits}
+	for id, count := range partCandID {
+		match.Contributors = append(match.Contributors, Contributor{ID: id, NHits: count})
+	}
+	sort.Slice(match.Contributors, func(i, j int) bool {
+		ci, cj := match.Contributors[i], match.Contributors[j]
+		if ci.NHits != cj.NHits {
+			return ci.NHits > cj.NHits
+		}
+		return ci.ID < cj.ID
+	})
+
+	if len(match.Contributors) == 0 {
+		return match
+	}
+
+	match.ParticleID = match.Contributors[0].ID
+	match.NShared = match.Contributors[0].NHits
+	match.Purity = float64(match.NShared) / float64(nHits)
+
+	switch m.Policy {
+	case MinPurity:
+		if match.Purity < m.MinPurity {
+			return match
+		}
+	case MinShared:
+		if match.NShared < m.MinShared {
+			return match
+		}
+	}
+
+	match.Particle, _ = event.GetEntry(match.ParticleID).(*eic.Particle)
+	return match
+}
+
+// MatchTagged matches every track carrying the given tag, keyed by entry ID.
+func (m *Matcher) MatchTagged(event *proio.Event, tag string) map[uint64]*Match {
+	matches := make(map[uint64]*Match)
+	for _, id := range event.TaggedEntries(tag) {
+		track, ok := event.GetEntry(id).(*eic.Track)
+		if !ok {
+			continue
+		}
+		matches[id] = m.Match(event, track)
+	}
+	return matches
+}
+
+// NewFlagMatcher returns a Matcher configured by the -match, -matchpurity and
+// -matchhits command-line flags, which it registers with the flag package.
+func NewFlagMatcher() *Matcher {
+	m := &Matcher{Policy: Majority}
+	flag.Var(&m.Policy, "match", "truth matching policy (majority, purity or shared)")
+	flag.Float64Var(&m.MinPurity, "matchpurity", 0.5, "minimum fraction of track hits from the matched particle for the purity policy")
+	flag.IntVar(&m.MinShared, "matchhits", 3, "minimum number of track hits from the matched particle for the shared policy")
+	return m
+}
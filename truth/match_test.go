@@ -0,0 +1,120 @@
+package truth
+
+import (
+	"testing"
+
+	"github.com/proio-org/go-proio"
+	"github.com/proio-org/go-proio-pb/model/eic"
+)
+
+// testEvent holds two generated particles, and adds energy deposits from
+// them through SimHits or directly.
+type testEvent struct {
+	*proio.Event
+	part1, part2 uint64
+}
+
+func newTestEvent() *testEvent {
+	event := proio.NewEvent()
+	return &testEvent{
+		Event: event,
+		part1: event.AddEntry("GenStable", &eic.Particle{Pdg: pdg(211), Charge: charge(1)}),
+		part2: event.AddEntry("GenStable", &eic.Particle{Pdg: pdg(-211), Charge: charge(-1)}),
+	}
+}
+
+func pdg(v int32) *int32        { return &v }
+func charge(v float32) *float32 { return &v }
+
+// simHitDeps adds n deposits, each with a SimHit of particle id as source.
+func (e *testEvent) simHitDeps(id uint64, n int) []uint64 {
+	var ids []uint64
+	for i := 0; i < n; i++ {
+		hit := e.AddEntry("Sim", &eic.SimHit{Particle: &id})
+		ids = append(ids, e.AddEntry("Tracker", &eic.EnergyDep{Source: []uint64{hit}}))
+	}
+	return ids
+}
+
+// directDeps adds n deposits with particle id itself as source.
+func (e *testEvent) directDeps(id uint64, n int) []uint64 {
+	var ids []uint64
+	for i := 0; i < n; i++ {
+		ids = append(ids, e.AddEntry("Tracker", &eic.EnergyDep{Source: []uint64{id}}))
+	}
+	return ids
+}
+
+func (e *testEvent) track(obs ...[]uint64) *eic.Track {
+	track := &eic.Track{}
+	for _, ids := range obs {
+		track.Observation = append(track.Observation, ids...)
+	}
+	e.AddEntry("Reconstructed", track)
+	return track
+}
+
+func TestMatch(t *testing.T) {
+	e := newTestEvent()
+	mixed := e.track(e.simHitDeps(e.part1, 3), e.directDeps(e.part2, 1))
+	tied := e.track(e.directDeps(e.part2, 2), e.simHitDeps(e.part1, 2))
+	// observations that are not energy deposits carry no votes
+	noDeps := e.track([]uint64{e.part1, e.AddEntry("Sim", &eic.SimHit{Particle: &e.part1})})
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		track   *eic.Track
+		matched bool
+		id      uint64
+		nShared int
+		nHits   int
+	}{
+		{"majority", Matcher{Policy: Majority}, mixed, true, e.part1, 3, 4},
+		{"tie", Matcher{Policy: Majority}, tied, true, e.part1, 2, 4},
+		{"purity pass", Matcher{Policy: MinPurity, MinPurity: 0.75}, mixed, true, e.part1, 3, 4},
+		{"purity fail", Matcher{Policy: MinPurity, MinPurity: 0.8}, mixed, false, e.part1, 3, 4},
+		{"shared pass", Matcher{Policy: MinShared, MinShared: 3}, mixed, true, e.part1, 3, 4},
+		{"shared fail", Matcher{Policy: MinShared, MinShared: 4}, mixed, false, e.part1, 3, 4},
+		{"no deposits", Matcher{Policy: Majority}, noDeps, false, 0, 0, 0},
+	}
+
+	for _, test := range tests {
+		match := test.matcher.Match(e.Event, test.track)
+		if match.Matched() != test.matched {
+			t.Errorf("%v: Matched = %v, want %v", test.name, match.Matched(), test.matched)
+		}
+		if match.ParticleID != test.id || match.NShared != test.nShared || match.NHits != test.nHits {
+			t.Errorf("%v: ID, NShared, NHits = %v, %v, %v, want %v, %v, %v",
+				test.name, match.ParticleID, match.NShared, match.NHits, test.id, test.nShared, test.nHits)
+		}
+		if test.matched && match.Particle != e.GetEntry(test.id) {
+			t.Errorf("%v: Particle is not entry %v", test.name, test.id)
+		}
+	}
+
+	match := (&Matcher{}).Match(e.Event, mixed)
+	if match.Purity != 0.75 {
+		t.Errorf("Purity = %v, want 0.75", match.Purity)
+	}
+	want := []Contributor{{e.part1, 3}, {e.part2, 1}}
+	if len(match.Contributors) != len(want) || match.Contributors[0] != want[0] || match.Contributors[1] != want[1] {
+		t.Errorf("Contributors = %v, want %v", match.Contributors, want)
+	}
+}
+
+func TestMatchTagged(t *testing.T) {
+	e := newTestEvent()
+	e.track(e.simHitDeps(e.part2, 2))
+	e.AddEntry("Reconstructed", &eic.Particle{})
+
+	matches := (&Matcher{}).MatchTagged(e.Event, "Reconstructed")
+	if len(matches) != 1 {
+		t.Fatalf("MatchTagged found %v tracks, want 1", len(matches))
+	}
+	for _, match := range matches {
+		if match.ParticleID != e.part2 {
+			t.Errorf("ParticleID = %v, want %v", match.ParticleID, e.part2)
+		}
+	}
+}